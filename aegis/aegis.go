@@ -0,0 +1,399 @@
+// Package aegis reads and writes vaults from the Aegis Authenticator Android
+// app.
+//
+// Both plaintext and encrypted vaults are supported. Encrypted vaults are
+// decrypted with a password slot; other slot types (such as biometric slots)
+// are ignored.
+package aegis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/internal/scrypt"
+)
+
+// Entry is an entry in an Aegis vault.
+type Entry struct {
+	Key otp.Key
+
+	UUID     string
+	Note     string
+	Favorite bool
+}
+
+// Scrypt parameters for new password slots; these are the same as Aegis uses.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Maximum scrypt parameters for reading. scrypt needs 128·N·r bytes of memory
+// and time proportional to N·r·p, so without a limit a vault could make us use
+// any amount of either.
+const (
+	maxScryptN  = 1 << 20
+	maxScryptRP = 16
+)
+
+// Slot type for password slots; the other types are for keys stored in the
+// Android keystore.
+const slotPassword = 1
+
+type (
+	vault struct {
+		Version int             `json:"version"`
+		Header  header          `json:"header"`
+		DB      json.RawMessage `json:"db"`
+	}
+	header struct {
+		Slots  []slot     `json:"slots"`
+		Params *keyParams `json:"params"`
+	}
+	slot struct {
+		Type      int       `json:"type"`
+		UUID      string    `json:"uuid"`
+		Key       string    `json:"key"`
+		KeyParams keyParams `json:"key_params"`
+		N         int       `json:"n,omitempty"`
+		R         int       `json:"r,omitempty"`
+		P         int       `json:"p,omitempty"`
+		Salt      string    `json:"salt,omitempty"`
+		Repaired  bool      `json:"repaired,omitempty"`
+		IsBackup  bool      `json:"is_backup,omitempty"`
+	}
+	keyParams struct {
+		Nonce string `json:"nonce"`
+		Tag   string `json:"tag"`
+	}
+	db struct {
+		Version int     `json:"version"`
+		Entries []entry `json:"entries"`
+	}
+	entry struct {
+		Type     string  `json:"type"`
+		UUID     string  `json:"uuid"`
+		Name     string  `json:"name"`
+		Issuer   string  `json:"issuer"`
+		Note     string  `json:"note"`
+		Favorite bool    `json:"favorite"`
+		Icon     *string `json:"icon"`
+		Info     info    `json:"info"`
+	}
+	info struct {
+		Secret  string  `json:"secret"`
+		Algo    string  `json:"algo"`
+		Digits  int     `json:"digits"`
+		Period  int     `json:"period,omitempty"`
+		Counter *uint64 `json:"counter,omitempty"`
+	}
+)
+
+// Read all entries from an Aegis vault.
+//
+// The password is only used for encrypted vaults. Only TOTP, HOTP, and Steam
+// entries are supported; an error is returned for any other entry.
+func Read(r io.Reader, password string) ([]Entry, error) {
+	var v vault
+	err := json.NewDecoder(r).Decode(&v)
+	if err != nil {
+		return nil, fmt.Errorf("aegis.Read: %w", err)
+	}
+	if v.Version != 1 {
+		return nil, fmt.Errorf("aegis.Read: unsupported vault version %d", v.Version)
+	}
+
+	plain := []byte(v.DB)
+	if v.Header.Params != nil {
+		if password == "" {
+			return nil, errors.New("aegis.Read: vault is encrypted, but password is empty")
+		}
+		plain, err = decryptDB(v, password)
+		if err != nil {
+			return nil, fmt.Errorf("aegis.Read: %w", err)
+		}
+	}
+
+	var d db
+	err = json.Unmarshal(plain, &d)
+	if err != nil {
+		return nil, fmt.Errorf("aegis.Read: %w", err)
+	}
+	if d.Version < 1 || d.Version > 3 {
+		return nil, fmt.Errorf("aegis.Read: unsupported database version %d", d.Version)
+	}
+
+	entries := make([]Entry, 0, len(d.Entries))
+	for i, e := range d.Entries {
+		k, err := fromEntry(e)
+		if err != nil {
+			return nil, fmt.Errorf("aegis.Read: entry %d (%q): %w", i, e.Name, err)
+		}
+		entries = append(entries, k)
+	}
+	return entries, nil
+}
+
+func fromEntry(e entry) (Entry, error) {
+	k := Entry{
+		UUID:     e.UUID,
+		Note:     e.Note,
+		Favorite: e.Favorite,
+		Key: otp.Key{
+			Kind:    otp.Kind(e.Type),
+			Issuer:  e.Issuer,
+			Account: e.Name,
+			Digits:  e.Info.Digits,
+		},
+	}
+	switch k.Key.Kind {
+	case otp.KindTOTP, otp.KindSteam:
+		k.Key.Period = time.Duration(e.Info.Period) * time.Second
+	case otp.KindHOTP:
+		if e.Info.Counter == nil {
+			return Entry{}, errors.New("counter is missing")
+		}
+		k.Key.Counter = *e.Info.Counter
+	default:
+		return Entry{}, fmt.Errorf("unsupported entry type %q", e.Type)
+	}
+
+	var err error
+	if k.Key.Algorithm, err = otp.ParseAlgorithm(e.Info.Algo); err != nil {
+		return Entry{}, err
+	}
+	k.Key.Secret, err = otp.ParseSecretWith(e.Info.Secret, otp.ParseSecretOptions{
+		Encoding:         otp.EncodingBase32,
+		AllowShortSecret: true,
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	k.Key, err = k.Key.Normalize()
+	return k, err
+}
+
+// decryptDB decrypts the master key from the first password slot that works,
+// and then decrypts the database with it.
+func decryptDB(v vault, password string) ([]byte, error) {
+	var ct string
+	err := json.Unmarshal(v.DB, &ct)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted db: %w", err)
+	}
+	db, err := base64.StdEncoding.DecodeString(ct)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted db: %w", err)
+	}
+
+	var (
+		master []byte
+		tried  bool
+	)
+	for _, s := range v.Header.Slots {
+		if s.Type != slotPassword {
+			continue
+		}
+		tried = true
+		salt, err := hex.DecodeString(s.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("slot %s: invalid salt: %w", s.UUID, err)
+		}
+		if s.N > maxScryptN || s.R > maxScryptRP || s.P > maxScryptRP || s.R*s.P > maxScryptRP {
+			return nil, fmt.Errorf("slot %s: scrypt parameters N=%d r=%d p=%d are larger than the maximum of N=%d and r·p=%d",
+				s.UUID, s.N, s.R, s.P, maxScryptN, maxScryptRP)
+		}
+		key, err := scrypt.Key([]byte(password), salt, s.N, s.R, s.P, 32)
+		if err != nil {
+			return nil, fmt.Errorf("slot %s: %w", s.UUID, err)
+		}
+		enc, err := hex.DecodeString(s.Key)
+		if err != nil {
+			return nil, fmt.Errorf("slot %s: invalid key: %w", s.UUID, err)
+		}
+		master, err = open(key, s.KeyParams, enc)
+		if err == nil {
+			break
+		}
+	}
+	if !tried {
+		return nil, errors.New("vault has no password slots")
+	}
+	if master == nil {
+		return nil, errors.New("wrong password")
+	}
+
+	plain, err := open(master, *v.Header.Params, db)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting db: %w", err)
+	}
+	return plain, nil
+}
+
+// Write entries as an Aegis vault.
+//
+// The vault is encrypted with a password slot if password isn't empty.
+func Write(w io.Writer, entries []Entry, password string) error {
+	d := db{Version: 2, Entries: make([]entry, 0, len(entries))}
+	for i, k := range entries {
+		e, err := toEntry(k)
+		if err != nil {
+			return fmt.Errorf("aegis.Write: entry %d: %w", i, err)
+		}
+		d.Entries = append(d.Entries, e)
+	}
+	plain, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("aegis.Write: %w", err)
+	}
+
+	v := vault{Version: 1, Header: header{}, DB: plain}
+	if password != "" {
+		v.Header, v.DB, err = encryptDB(plain, password)
+		if err != nil {
+			return fmt.Errorf("aegis.Write: %w", err)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	err = enc.Encode(v)
+	if err != nil {
+		return fmt.Errorf("aegis.Write: %w", err)
+	}
+	return nil
+}
+
+func toEntry(k Entry) (entry, error) {
+	key, err := k.Key.Normalize()
+	if err != nil {
+		return entry{}, err
+	}
+	alg, err := otp.FormatAlgorithm(key.Algorithm)
+	if err != nil {
+		return entry{}, err
+	}
+
+	e := entry{
+		Type:     string(key.Kind),
+		UUID:     k.UUID,
+		Name:     key.Account,
+		Issuer:   key.Issuer,
+		Note:     k.Note,
+		Favorite: k.Favorite,
+		Info: info{
+			Secret: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key.Secret),
+			Algo:   alg,
+			Digits: key.Digits,
+		},
+	}
+	if e.UUID == "" {
+		e.UUID = newUUID()
+	}
+	if key.Kind == otp.KindHOTP {
+		e.Info.Counter = &key.Counter
+	} else {
+		if !key.T0.IsZero() {
+			return entry{}, errors.New("T0 not supported")
+		}
+		e.Info.Period = int(key.Period / time.Second)
+	}
+	return e, nil
+}
+
+func encryptDB(plain []byte, password string) (header, json.RawMessage, error) {
+	var (
+		master = make([]byte, 32)
+		salt   = make([]byte, 32)
+	)
+	_, _ = rand.Read(master) // Documented as never returning an error
+	_, _ = rand.Read(salt)
+
+	key, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return header{}, nil, err
+	}
+
+	s := slot{
+		Type:     slotPassword,
+		UUID:     newUUID(),
+		N:        scryptN,
+		R:        scryptR,
+		P:        scryptP,
+		Salt:     hex.EncodeToString(salt),
+		Repaired: true,
+	}
+	var enc []byte
+	s.KeyParams, enc = seal(key, master)
+	s.Key = hex.EncodeToString(enc)
+
+	params, ct := seal(master, plain)
+	db, err := json.Marshal(base64.StdEncoding.EncodeToString(ct))
+	if err != nil {
+		return header{}, nil, err
+	}
+	return header{Slots: []slot{s}, Params: &params}, db, nil
+}
+
+// open decrypts ct with AES-GCM; Aegis stores the tag separately.
+func open(key []byte, p keyParams, ct []byte) ([]byte, error) {
+	nonce, err := hex.DecodeString(p.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	tag, err := hex.DecodeString(p.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag: %w", err)
+	}
+
+	gcm, err := newGCM(key, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+	if len(tag) != gcm.Overhead() {
+		return nil, fmt.Errorf("tag is %d bytes, not %d", len(tag), gcm.Overhead())
+	}
+	return gcm.Open(nil, nonce, append(ct[:len(ct):len(ct)], tag...), nil)
+}
+
+func seal(key, plain []byte) (keyParams, []byte) {
+	nonce := make([]byte, 12)
+	_, _ = rand.Read(nonce)
+
+	gcm, _ := newGCM(key, len(nonce)) // Key and nonce are always valid.
+	ct := gcm.Seal(nil, nonce, plain, nil)
+	tag := len(ct) - gcm.Overhead()
+	return keyParams{Nonce: hex.EncodeToString(nonce), Tag: hex.EncodeToString(ct[tag:])}, ct[:tag]
+}
+
+func newGCM(key []byte, nonceSize int) (cipher.AEAD, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if nonceSize == 0 {
+		return nil, errors.New("nonce is empty")
+	}
+	return cipher.NewGCMWithNonceSize(b, nonceSize)
+}
+
+// newUUID creates a random (version 4) UUID.
+func newUUID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
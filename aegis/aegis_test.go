@@ -0,0 +1,268 @@
+package aegis_test
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/aegis"
+)
+
+const plain = `{
+    "version": 1,
+    "header": {
+        "slots": null,
+        "params": null
+    },
+    "db": {
+        "version": 2,
+        "entries": [
+            {
+                "type": "totp",
+                "uuid": "3ae6f1ad-2e65-4ed2-a953-1ec0dff2386d",
+                "name": "Mason",
+                "issuer": "Deno",
+                "note": "",
+                "favorite": true,
+                "icon": null,
+                "info": {"secret": "4SJHB4GSD43FZBAI7C2HLRJGPQ", "algo": "SHA1", "digits": 6, "period": 30}
+            },
+            {
+                "type": "totp",
+                "uuid": "912e9d36-5a8e-4e55-a58d-8ed3c5e2e14e",
+                "name": "James",
+                "issuer": "SPDX",
+                "note": "Work",
+                "favorite": false,
+                "icon": null,
+                "info": {"secret": "5OM4WOOGPLQEF6UGN3CPEOOLWU", "algo": "SHA256", "digits": 7, "period": 20}
+            },
+            {
+                "type": "hotp",
+                "uuid": "e5ee7d32-5e6a-4e0c-9f2b-6b3c0d0c9a51",
+                "name": "Benjamin",
+                "issuer": "Air Canada",
+                "note": "",
+                "favorite": false,
+                "icon": null,
+                "info": {"secret": "KUVJJOM753IHTNDSZVCNKL7GII", "algo": "SHA512", "digits": 8, "counter": 50}
+            },
+            {
+                "type": "steam",
+                "uuid": "4f2c4a4e-f0a0-4c2f-9a3e-7bb0b8b1b6a5",
+                "name": "Sophia",
+                "issuer": "Steam",
+                "note": "",
+                "favorite": false,
+                "icon": null,
+                "info": {"secret": "JRZCL47CMXVOQMNPZR2F7J4RGI", "algo": "SHA1", "digits": 5, "period": 30}
+            }
+        ]
+    }
+}`
+
+var want = []aegis.Entry{
+	{Key: otp.Key{Kind: otp.KindTOTP, Issuer: "Deno", Account: "Mason", Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second,
+		Secret: []byte{0xe4, 0x92, 0x70, 0xf0, 0xd2, 0x1f, 0x36, 0x5c, 0x84, 0x08, 0xf8, 0xb4, 0x75, 0xc5, 0x26, 0x7c}},
+		UUID: "3ae6f1ad-2e65-4ed2-a953-1ec0dff2386d", Favorite: true},
+	{Key: otp.Key{Kind: otp.KindTOTP, Issuer: "SPDX", Account: "James", Algorithm: crypto.SHA256, Digits: 7, Period: 20 * time.Second,
+		Secret: []byte{0xeb, 0x99, 0xcb, 0x39, 0xc6, 0x7a, 0xe0, 0x42, 0xfa, 0x86, 0x6e, 0xc4, 0xf2, 0x39, 0xcb, 0xb5}},
+		UUID: "912e9d36-5a8e-4e55-a58d-8ed3c5e2e14e", Note: "Work"},
+	{Key: otp.Key{Kind: otp.KindHOTP, Issuer: "Air Canada", Account: "Benjamin", Algorithm: crypto.SHA512, Digits: 8, Counter: 50,
+		Secret: []byte{0x55, 0x2a, 0x94, 0xb9, 0x9f, 0xee, 0xd0, 0x79, 0xb4, 0x72, 0xcd, 0x44, 0xd5, 0x2f, 0xe6, 0x42}},
+		UUID: "e5ee7d32-5e6a-4e0c-9f2b-6b3c0d0c9a51"},
+	{Key: otp.Key{Kind: otp.KindSteam, Issuer: "Steam", Account: "Sophia", Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second,
+		Secret: []byte{0x4c, 0x72, 0x25, 0xf3, 0xe2, 0x65, 0xea, 0xe8, 0x31, 0xaf, 0xcc, 0x74, 0x5f, 0xa7, 0x91, 0x32}},
+		UUID: "4f2c4a4e-f0a0-4c2f-9a3e-7bb0b8b1b6a5"},
+}
+
+func TestRead(t *testing.T) {
+	have, err := aegis.Read(strings.NewReader(plain), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+	}
+
+	for _, e := range have {
+		if _, err := e.Key.Generator(); err != nil {
+			t.Errorf("%s: %s", e.Key.Account, err)
+		}
+	}
+}
+
+// testdata/encrypted.json is plain encrypted with the password "test". It wasn't
+// created with Write(), but independently from the Aegis vault format, to
+// catch mistakes that Write() and Read() would share.
+func TestReadEncrypted(t *testing.T) {
+	data, err := os.ReadFile("testdata/encrypted.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := aegis.Read(bytes.NewReader(data), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+	}
+
+	_, err = aegis.Read(bytes.NewReader(data), "test2")
+	if err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("wrong error for wrong password: %v", err)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	tests := []struct {
+		in, wantErr string
+	}{
+		{strings.Replace(plain, `"type": "hotp"`, `"type": "motp"`, 1), `entry 2 ("Benjamin"): unsupported entry type "motp"`},
+		{strings.Replace(plain, `"SHA512"`, `"MD5"`, 1), `unsupported algorithm "MD5"`},
+		{strings.Replace(plain, `"counter": 50`, `"x": 50`, 1), `counter is missing`},
+		{strings.Replace(plain, `"period": 20`, `"period": -1`, 1), `invalid period -1s`},
+		{strings.Replace(plain, `4SJHB4GSD43FZBAI7C2HLRJGPQ`, `1234`, 1), `otp.ParseSecret: invalid character '1'`},
+		{strings.Replace(plain, `"version": 1`, `"version": 2`, 1), `unsupported vault version 2`},
+		{strings.Replace(plain, `"version": 2`, `"version": 4`, 1), `unsupported database version 4`},
+		{`{`, `unexpected EOF`},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := aegis.Read(strings.NewReader(tt.in), "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, password := range []string{"", "hunter2"} {
+		t.Run(password, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := aegis.Write(&buf, want, password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isEnc := !bytes.Contains(buf.Bytes(), []byte("4SJHB4GSD43FZBAI7C2HLRJGPQ")); isEnc != (password != "") {
+				t.Fatalf("encrypted=%t; password=%q\n%s", isEnc, password, buf.String())
+			}
+
+			have, err := aegis.Read(bytes.NewReader(buf.Bytes()), password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+			}
+
+			if password != "" {
+				_, err := aegis.Read(bytes.NewReader(buf.Bytes()), "hunter3")
+				if err == nil || !strings.Contains(err.Error(), "wrong password") {
+					t.Errorf("wrong error for wrong password: %v", err)
+				}
+				_, err = aegis.Read(bytes.NewReader(buf.Bytes()), "")
+				if err == nil || !strings.Contains(err.Error(), "password is empty") {
+					t.Errorf("wrong error for empty password: %v", err)
+				}
+
+				// Modify the ciphertext.
+				var v map[string]any
+				_ = json.Unmarshal(buf.Bytes(), &v)
+				db, err := base64.StdEncoding.DecodeString(v["db"].(string))
+				if err != nil {
+					t.Fatal(err)
+				}
+				db[0] ^= 1
+				v["db"] = base64.StdEncoding.EncodeToString(db)
+				tamp, _ := json.Marshal(v)
+				_, err = aegis.Read(bytes.NewReader(tamp), password)
+				if err == nil || !strings.Contains(err.Error(), "decrypting db") {
+					t.Errorf("wrong error for modified db: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestReadScryptLimit(t *testing.T) {
+	var buf bytes.Buffer
+	err := aegis.Write(&buf, want, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n, r, p int
+		wantErr string
+	}{
+		{1 << 50, 1, 1, "N=1125899906842624 r=1 p=1 are larger than the maximum"},
+		{1 << 21, 8, 1, "N=2097152 r=8 p=1 are larger than the maximum"},
+		{1 << 15, 17, 1, "N=32768 r=17 p=1 are larger than the maximum"},
+		{1 << 15, 8, 4, "N=32768 r=8 p=4 are larger than the maximum"},
+		{1 << 15, 1 << 62, 1 << 62, "are larger than the maximum"},
+	}
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			var v map[string]any
+			_ = json.Unmarshal(buf.Bytes(), &v)
+			slot := v["header"].(map[string]any)["slots"].([]any)[0].(map[string]any)
+			slot["n"], slot["r"], slot["p"] = tt.n, tt.r, tt.p
+			vault, _ := json.Marshal(v)
+
+			_, err := aegis.Read(bytes.NewReader(vault), "hunter2")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteNew(t *testing.T) {
+	var buf bytes.Buffer
+	err := aegis.Write(&buf, []aegis.Entry{{Key: otp.Key{Secret: []byte("12345678901234567890"), Account: "a"}}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := aegis.Read(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have[0].UUID) != 36 {
+		t.Errorf("UUID: %q", have[0].UUID)
+	}
+	w := otp.Key{Kind: otp.KindTOTP, Secret: []byte("12345678901234567890"), Account: "a",
+		Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}
+	if !reflect.DeepEqual(have[0].Key, w) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have[0].Key, w)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		in      otp.Key
+		wantErr string
+	}{
+		{otp.Key{}, "secret is empty"},
+		{otp.Key{Secret: []byte("a"), Algorithm: crypto.MD5}, "algorithm MD5 not supported"},
+		{otp.Key{Secret: []byte("a"), T0: time.Unix(1, 0)}, "T0 not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			err := aegis.Write(new(bytes.Buffer), []aegis.Entry{{Key: tt.in}}, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
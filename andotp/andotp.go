@@ -0,0 +1,258 @@
+// Package andotp reads and writes backups from the andOTP Android app.
+//
+// Both plain JSON backups and encrypted backups (.json.aes) are supported.
+// Backups encrypted with the old format from before andOTP 0.6.3, which used
+// SHA-256 of the password as the key, are not supported.
+package andotp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"zgo.at/otp"
+)
+
+// Entry is an entry in an andOTP backup.
+type Entry struct {
+	Key otp.Key
+
+	Tags []string
+}
+
+// Parameters for encrypted backups.
+const (
+	iterations = 150_000 // andOTP picks a random number between 140,000 and 160,000.
+	saltSize   = 12
+	nonceSize  = 12
+	headerSize = 4 + saltSize + nonceSize
+
+	// Maximum number of iterations when reading, so that a backup can't make
+	// us spend an unreasonable amount of time on PBKDF2.
+	maxIterations = 10_000_000
+)
+
+type entry struct {
+	Secret        string   `json:"secret"`
+	Issuer        string   `json:"issuer"`
+	Label         string   `json:"label"`
+	Digits        int      `json:"digits"`
+	Type          string   `json:"type"`
+	Algorithm     string   `json:"algorithm"`
+	Thumbnail     string   `json:"thumbnail"`
+	LastUsed      int64    `json:"last_used"`
+	UsedFrequency int      `json:"used_frequency"`
+	Period        int      `json:"period,omitempty"`
+	Counter       *uint64  `json:"counter,omitempty"`
+	Tags          []string `json:"tags"`
+}
+
+// Read all entries from an andOTP backup.
+//
+// Encrypted backups are detected automatically; the password is only used for
+// encrypted backups. Only TOTP, HOTP, and Steam entries are supported; an error
+// is returned for any other entry.
+func Read(r io.Reader, password string) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("andotp.Read: %w", err)
+	}
+
+	if t := bytes.TrimLeft(data, " \t\r\n"); len(t) == 0 || t[0] != '[' {
+		if password == "" {
+			return nil, errors.New("andotp.Read: backup is encrypted, but password is empty")
+		}
+		data, err = decrypt(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("andotp.Read: %w", err)
+		}
+	}
+
+	var list []entry
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, fmt.Errorf("andotp.Read: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(list))
+	for i, e := range list {
+		k, err := fromEntry(e)
+		if err != nil {
+			return nil, fmt.Errorf("andotp.Read: entry %d (%q): %w", i, e.Label, err)
+		}
+		entries = append(entries, k)
+	}
+	return entries, nil
+}
+
+func fromEntry(e entry) (Entry, error) {
+	k := Entry{
+		Tags: e.Tags,
+		Key: otp.Key{
+			Issuer:  e.Issuer,
+			Account: e.Label,
+			Digits:  e.Digits,
+			Period:  time.Duration(e.Period) * time.Second,
+		},
+	}
+	switch strings.ToUpper(e.Type) {
+	case "TOTP":
+		k.Key.Kind = otp.KindTOTP
+	case "STEAM":
+		k.Key.Kind = otp.KindSteam
+	case "HOTP":
+		k.Key.Kind, k.Key.Period = otp.KindHOTP, 0
+		if e.Counter == nil {
+			return Entry{}, errors.New("counter is missing")
+		}
+		k.Key.Counter = *e.Counter
+	default:
+		return Entry{}, fmt.Errorf("unsupported entry type %q", e.Type)
+	}
+
+	var err error
+	if e.Algorithm != "" {
+		if k.Key.Algorithm, err = otp.ParseAlgorithm(e.Algorithm); err != nil {
+			return Entry{}, err
+		}
+	}
+	k.Key.Secret, err = otp.ParseSecretWith(e.Secret, otp.ParseSecretOptions{
+		Encoding:         otp.EncodingBase32,
+		AllowShortSecret: true,
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	k.Key, err = k.Key.Normalize()
+	return k, err
+}
+
+// Write entries as an andOTP backup.
+//
+// The backup is encrypted if password isn't empty; this should be saved with
+// the .json.aes extension.
+func Write(w io.Writer, entries []Entry, password string) error {
+	list := make([]entry, 0, len(entries))
+	for i, k := range entries {
+		e, err := toEntry(k)
+		if err != nil {
+			return fmt.Errorf("andotp.Write: entry %d: %w", i, err)
+		}
+		list = append(list, e)
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("andotp.Write: %w", err)
+	}
+	if password != "" {
+		data, err = encrypt(data, password)
+		if err != nil {
+			return fmt.Errorf("andotp.Write: %w", err)
+		}
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("andotp.Write: %w", err)
+	}
+	return nil
+}
+
+func toEntry(k Entry) (entry, error) {
+	key, err := k.Key.Normalize()
+	if err != nil {
+		return entry{}, err
+	}
+	alg, err := otp.FormatAlgorithm(key.Algorithm)
+	if err != nil {
+		return entry{}, err
+	}
+
+	e := entry{
+		Secret:    base32.StdEncoding.EncodeToString(key.Secret),
+		Issuer:    key.Issuer,
+		Label:     key.Account,
+		Digits:    key.Digits,
+		Type:      strings.ToUpper(string(key.Kind)),
+		Algorithm: alg,
+		Thumbnail: "Default",
+		Tags:      k.Tags,
+	}
+	if e.Tags == nil {
+		e.Tags = []string{}
+	}
+	if key.Kind == otp.KindHOTP {
+		e.Counter = &key.Counter
+	} else {
+		if !key.T0.IsZero() {
+			return entry{}, errors.New("T0 not supported")
+		}
+		e.Period = int(key.Period / time.Second)
+	}
+	return e, nil
+}
+
+// decrypt an encrypted backup; the format is:
+//
+//	iterations (uint32, big endian) || salt || nonce || ciphertext || tag
+//
+// The key is derived with PBKDF2-HMAC-SHA1.
+func decrypt(data []byte, password string) ([]byte, error) {
+	if len(data) < headerSize {
+		return nil, errors.New("encrypted backup is too short")
+	}
+	var (
+		iter  = binary.BigEndian.Uint32(data)
+		salt  = data[4 : 4+saltSize]
+		nonce = data[4+saltSize : headerSize]
+	)
+	if iter == 0 || iter > maxIterations {
+		return nil, fmt.Errorf("invalid number of PBKDF2 iterations: %d", iter)
+	}
+
+	gcm, err := newGCM(password, salt, int(iter))
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, data[headerSize:], nil)
+	if err != nil {
+		return nil, errors.New("wrong password or corrupt backup")
+	}
+	return plain, nil
+}
+
+func encrypt(plain []byte, password string) ([]byte, error) {
+	data := make([]byte, headerSize, headerSize+len(plain)+16)
+	binary.BigEndian.PutUint32(data, iterations)
+	_, _ = rand.Read(data[4:]) // Documented as never returning an error
+
+	gcm, err := newGCM(password, data[4:4+saltSize], iterations)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(data, data[4+saltSize:headerSize], plain, nil), nil
+}
+
+func newGCM(password string, salt []byte, iter int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha1.New, password, salt, iter, 32)
+	if err != nil {
+		return nil, err
+	}
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(b)
+}
@@ -0,0 +1,152 @@
+package andotp_test
+
+import (
+	"bytes"
+	"crypto"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/andotp"
+)
+
+const plain = `[
+	{"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","issuer":"Example","label":"alice@example.com","digits":6,"type":"TOTP","algorithm":"SHA1","thumbnail":"Default","last_used":1700000000000,"used_frequency":3,"period":30,"tags":["work"]},
+	{"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","issuer":"","label":"bob","digits":8,"type":"HOTP","algorithm":"SHA256","thumbnail":"Default","last_used":0,"used_frequency":0,"counter":5,"tags":[]},
+	{"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","issuer":"Steam","label":"carol","digits":5,"type":"STEAM","algorithm":"SHA1","thumbnail":"Steam","last_used":0,"used_frequency":0,"period":30,"tags":[]}
+]`
+
+var (
+	secret = []byte("12345678901234567890")
+	want   = []andotp.Entry{
+		{Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: "alice@example.com",
+			Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, Tags: []string{"work"}},
+		{Key: otp.Key{Kind: otp.KindHOTP, Secret: secret, Account: "bob",
+			Algorithm: crypto.SHA256, Digits: 8, Counter: 5}, Tags: []string{}},
+		{Key: otp.Key{Kind: otp.KindSteam, Secret: secret, Issuer: "Steam", Account: "carol",
+			Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, Tags: []string{}},
+	}
+)
+
+func TestRead(t *testing.T) {
+	have, err := andotp.Read(strings.NewReader(plain), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+	}
+
+	g, err := have[0].Key.Generator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := g.TokenAt(time.Unix(59, 0)); err != nil || tok != "287082" {
+		t.Errorf("wrong token: %q", tok)
+	}
+}
+
+// testdata/encrypted.bin is plain encrypted with the password "test". It wasn't
+// created with Write(), but independently from the andOTP backup format, to
+// catch mistakes that Write() and Read() would share.
+func TestReadEncrypted(t *testing.T) {
+	data, err := os.ReadFile("testdata/encrypted.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := andotp.Read(bytes.NewReader(data), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+	}
+
+	_, err = andotp.Read(bytes.NewReader(data), "test2")
+	if err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("wrong error for wrong password: %v", err)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	tests := []struct {
+		in, wantErr string
+	}{
+		{strings.Replace(plain, `"type":"HOTP"`, `"type":"MOTP"`, 1), `entry 1 ("bob"): unsupported entry type "MOTP"`},
+		{strings.Replace(plain, `"counter":5,`, ``, 1), `counter is missing`},
+		{strings.Replace(plain, `"SHA256"`, `"MD5"`, 1), `unsupported algorithm "MD5"`},
+		{strings.Replace(plain, `"digits":8`, `"digits":11`, 1), `invalid digits 11`},
+		{strings.Replace(plain, `GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ`, `1`, 1), `otp.ParseSecret: invalid character '1'`},
+		{`[{`, `unexpected end of JSON input`},
+		{"\x00\x00\x03\xe8", `password is empty`},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := andotp.Read(strings.NewReader(tt.in), "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, password := range []string{"", "hunter2"} {
+		t.Run(password, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := andotp.Write(&buf, want, password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isEnc := !bytes.Contains(buf.Bytes(), []byte("GEZDGNBV")); isEnc != (password != "") {
+				t.Fatalf("encrypted=%t; password=%q\n%s", isEnc, password, buf.String())
+			}
+
+			have, err := andotp.Read(bytes.NewReader(buf.Bytes()), password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+			}
+
+			if password != "" {
+				_, err := andotp.Read(bytes.NewReader(buf.Bytes()), "hunter3")
+				if err == nil || !strings.Contains(err.Error(), "wrong password") {
+					t.Errorf("wrong error for wrong password: %v", err)
+				}
+
+				b := buf.Bytes()
+				b[len(b)-1] ^= 1
+				_, err = andotp.Read(bytes.NewReader(b), password)
+				if err == nil || !strings.Contains(err.Error(), "corrupt backup") {
+					t.Errorf("wrong error for modified backup: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		in      otp.Key
+		wantErr string
+	}{
+		{otp.Key{}, "secret is empty"},
+		{otp.Key{Secret: secret, Algorithm: crypto.MD5}, "algorithm MD5 not supported"},
+		{otp.Key{Secret: secret, T0: time.Unix(1, 0)}, "T0 not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			err := andotp.Write(new(bytes.Buffer), []andotp.Entry{{Key: tt.in}}, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package bitwarden converts the TOTP field of Bitwarden logins.
+//
+// The login.totp field can contain a bare base32 secret, an otpauth:// URL, or
+// a steam:// URL for Steam Guard.
+package bitwarden
+
+import (
+	"crypto"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"zgo.at/otp"
+)
+
+// ParseTOTP parses the login.totp field.
+//
+// A bare secret is a TOTP key with the default parameters: SHA1, 6 digits, and
+// a period of 30 seconds.
+//
+// Errors never include the field value, as it contains the secret.
+func ParseTOTP(s string) (otp.Key, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return otp.Key{}, errors.New("bitwarden.ParseTOTP: empty value")
+	case hasPrefixFold(s, "otpauth://"):
+		k, err := otp.ParseURL(s)
+		if err != nil {
+			return otp.Key{}, fmt.Errorf("bitwarden.ParseTOTP: %w", err)
+		}
+		return k, nil
+	case hasPrefixFold(s, "steam://"):
+		secret, err := otp.ParseSteamSecret(s)
+		if err != nil {
+			return otp.Key{}, fmt.Errorf("bitwarden.ParseTOTP: %w", err)
+		}
+		return otp.Key{Kind: otp.KindSteam, Secret: secret, Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, nil
+	default:
+		secret, err := otp.ParseSecretWith(s, otp.ParseSecretOptions{Encoding: otp.EncodingBase32, AllowShortSecret: true})
+		if err != nil {
+			return otp.Key{}, fmt.Errorf("bitwarden.ParseTOTP: %w", err)
+		}
+		return otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, nil
+	}
+}
+
+// FormatTOTP formats the key for the login.totp field.
+//
+// Steam keys are formatted as steam:// and all other keys as otpauth://, so
+// the issuer and account are preserved. Bitwarden can't generate HOTP tokens;
+// an error is returned for HOTP keys.
+func FormatTOTP(k otp.Key) (string, error) {
+	if len(k.Secret) == 0 {
+		return "", errors.New("bitwarden.FormatTOTP: secret is empty")
+	}
+	switch k.Kind {
+	case otp.KindTOTP, "":
+		return formatURL(k)
+	case otp.KindSteam:
+		n, err := k.Normalize()
+		if err != nil {
+			return "", fmt.Errorf("bitwarden.FormatTOTP: %w", err)
+		}
+		if n.Algorithm != crypto.SHA1 || n.Digits != 5 || n.Period != 30*time.Second || !n.T0.IsZero() {
+			// Non-standard parameters can't be represented with steam://.
+			return formatURL(k)
+		}
+		return "steam://" + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret), nil
+	default:
+		return "", fmt.Errorf("bitwarden.FormatTOTP: kind %q not supported", k.Kind)
+	}
+}
+
+func formatURL(k otp.Key) (string, error) {
+	u, err := k.URL()
+	if err != nil {
+		return "", fmt.Errorf("bitwarden.FormatTOTP: %w", err)
+	}
+	return u.String(), nil
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
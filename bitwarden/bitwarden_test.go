@@ -0,0 +1,104 @@
+package bitwarden_test
+
+import (
+	"crypto"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/bitwarden"
+)
+
+var secret = []byte("12345678901234567890")
+
+func TestParseTOTP(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    otp.Key
+		wantErr string
+	}{
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ""},
+		{" gezd gnbv gy3t qojq gezd gnbv gy3t qojq ",
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ""},
+		{"steam://GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			otp.Key{Kind: otp.KindSteam, Secret: secret, Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, ""},
+		{"otpauth://totp/Example:alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example&digits=8&period=60&algorithm=SHA256",
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: "alice",
+				Algorithm: crypto.SHA256, Digits: 8, Period: 60 * time.Second}, ""},
+
+		{"", otp.Key{}, "empty value"},
+		{"steam://", otp.Key{}, "secret is empty"},
+		{"GEZDGNBV1", otp.Key{}, "otp.ParseSecret: invalid character '1'"},
+		{"otpauth://totp/x", otp.Key{}, "secret is missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := bitwarden.ParseTOTP(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+			if err != nil && strings.Contains(err.Error(), "GEZD") {
+				t.Errorf("error contains secret: %s", err)
+			}
+		})
+	}
+}
+
+func TestFormatTOTP(t *testing.T) {
+	tests := []struct {
+		in      otp.Key
+		want    string
+		wantErr string
+	}{
+		{otp.Key{Secret: secret}, "otpauth://totp/?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", ""},
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: "alice", Digits: 8},
+			"otpauth://totp/Example:alice?digits=8&issuer=Example&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", ""},
+		{otp.Key{Kind: otp.KindSteam, Secret: secret, Digits: 5, Period: 30 * time.Second},
+			"steam://GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", ""},
+		{otp.Key{Kind: otp.KindSteam, Secret: secret, Period: time.Minute},
+			"otpauth://totp/?digits=5&encoder=steam&period=60&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", ""},
+
+		{otp.Key{Kind: otp.KindHOTP, Secret: secret}, "", `kind "hotp" not supported`},
+		{otp.Key{}, "", "secret is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := bitwarden.FormatTOTP(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+			if err != nil {
+				return
+			}
+
+			k, err := bitwarden.ParseTOTP(have)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(k.Secret) != string(tt.in.Secret) || k.Account != tt.in.Account || k.Issuer != tt.in.Issuer {
+				t.Errorf("round-trip:\nhave: %#v\nwant: %#v", k, tt.in)
+			}
+		})
+	}
+}
+
+func errorContains(err error, want string) bool {
+	if err == nil {
+		return want == ""
+	}
+	if want == "" {
+		return false
+	}
+	return strings.Contains(err.Error(), want)
+}
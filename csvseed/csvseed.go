@@ -0,0 +1,358 @@
+// Package csvseed reads and writes CSV seed files for hardware TOTP tokens.
+//
+// Vendors such as Token2 and Feitian deliver the seeds of their tokens as CSV
+// files. There isn't a single layout, but they all have a header and the same
+// basic information: the serial number, the seed, and sometimes the algorithm,
+// digits, and time step.
+package csvseed
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base32"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"zgo.at/otp"
+)
+
+// Row is a token in a seed file.
+//
+// Use Key.Generator() to create a generator for the token.
+type Row struct {
+	Key otp.Key
+
+	Line         int // Line number in the file; only set by Read().
+	Serial       string
+	Manufacturer string
+	Model        string
+}
+
+// Layout is the layout of a seed file.
+type Layout struct {
+	// Header of the file; every column must be one of the names accepted by
+	// Read().
+	Header []string
+
+	// Write the seed as base32 rather than hex.
+	Base32 bool
+}
+
+// Common layouts.
+var (
+	// Generic layout with a hex seed.
+	Generic = Layout{Header: []string{"serial", "seed", "algorithm", "digits", "time step"}}
+
+	// Azure is the layout for importing hardware tokens in Microsoft Entra ID
+	// (Azure AD), which many vendors also use for their seed files.
+	Azure = Layout{
+		Header: []string{"upn", "serial number", "secret key", "time interval", "manufacturer", "model"},
+		Base32: true,
+	}
+)
+
+type field int
+
+const (
+	fieldNone field = iota
+	fieldSerial
+	fieldSeed
+	fieldSeedHex
+	fieldSeedBase32
+	fieldAlgorithm
+	fieldDigits
+	fieldStep
+	fieldAccount
+	fieldManufacturer
+	fieldModel
+)
+
+var columns = map[string]field{
+	"serial":        fieldSerial,
+	"serial number": fieldSerial,
+	"serialnumber":  fieldSerial,
+	"serial no":     fieldSerial,
+	"sn":            fieldSerial,
+	"seed":          fieldSeed,
+	"secret":        fieldSeed,
+	"key":           fieldSeed,
+	"hex seed":      fieldSeedHex,
+	"seed (hex)":    fieldSeedHex,
+	"seed hex":      fieldSeedHex,
+	"secret key":    fieldSeedBase32,
+	"base32 seed":   fieldSeedBase32,
+	"seed (base32)": fieldSeedBase32,
+	"seed base32":   fieldSeedBase32,
+	"algorithm":     fieldAlgorithm,
+	"algo":          fieldAlgorithm,
+	"hash":          fieldAlgorithm,
+	"digits":        fieldDigits,
+	"length":        fieldDigits,
+	"otp length":    fieldDigits,
+	"time step":     fieldStep,
+	"timestep":      fieldStep,
+	"time interval": fieldStep,
+	"step":          fieldStep,
+	"period":        fieldStep,
+	"interval":      fieldStep,
+	"upn":           fieldAccount,
+	"account":       fieldAccount,
+	"user":          fieldAccount,
+	"manufacturer":  fieldManufacturer,
+	"model":         fieldModel,
+}
+
+// RowError is an error for a single row.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Err) }
+func (e RowError) Unwrap() error { return e.Err }
+
+// Read all rows from a CSV seed file.
+//
+// The first line must be a header; columns are matched by name (case
+// insensitive), and unknown columns are ignored. The delimiter may be a comma,
+// semicolon, or tab.
+//
+// The seed column can be named "seed", "secret", or "key", in which case hex
+// and base32 are detected automatically. Use "hex seed" or "base32 seed" to
+// make it explicit; "secret key" (as used by Azure) is always base32. Missing
+// values are set to the defaults: SHA1, 6 digits, and a time step of 30
+// seconds.
+//
+// Rows that are invalid are skipped; the returned error wraps a RowError for
+// every invalid row, and the valid rows are still returned.
+func Read(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("csvseed.Read: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff")) // Excel likes to add a BOM.
+
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.Comma = delimiter(data)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("csvseed.Read: file is empty")
+		}
+		return nil, fmt.Errorf("csvseed.Read: %w", err)
+	}
+	fields := make([]field, len(header))
+	var hasSeed bool
+	for i, h := range header {
+		fields[i] = columns[strings.ToLower(strings.TrimSpace(h))]
+		hasSeed = hasSeed || fields[i] == fieldSeed || fields[i] == fieldSeedHex || fields[i] == fieldSeedBase32
+	}
+	if !hasSeed {
+		return nil, errors.New("csvseed.Read: no seed column in header")
+	}
+
+	var (
+		rows []Row
+		errs []error
+	)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("csvseed.Read: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+
+		row, err := parseRow(fields, rec)
+		if err != nil {
+			errs = append(errs, RowError{Line: line, Err: err})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	if len(errs) > 0 {
+		return rows, fmt.Errorf("csvseed.Read: %w", errors.Join(errs...))
+	}
+	return rows, nil
+}
+
+func parseRow(fields []field, rec []string) (Row, error) {
+	row := Row{Key: otp.Key{Kind: otp.KindTOTP, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}}
+	for i, v := range rec {
+		if i >= len(fields) {
+			break
+		}
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		var err error
+		switch f := fields[i]; f {
+		case fieldSerial:
+			row.Serial = v
+		case fieldAccount:
+			row.Key.Account = v
+		case fieldManufacturer:
+			row.Manufacturer = v
+		case fieldModel:
+			row.Model = v
+		case fieldSeed, fieldSeedHex, fieldSeedBase32:
+			row.Key.Secret, err = decodeSeed(v, f)
+			if err != nil {
+				err = fmt.Errorf("invalid seed: %w", err)
+			}
+		case fieldAlgorithm:
+			row.Key.Algorithm, err = otp.ParseAlgorithm(strings.TrimPrefix(strings.ToUpper(v), "HMAC-"))
+			if err != nil {
+				err = fmt.Errorf("unsupported algorithm %q", v)
+			}
+		case fieldDigits:
+			row.Key.Digits, err = strconv.Atoi(v)
+			if err != nil || row.Key.Digits < 1 || row.Key.Digits > 10 {
+				err = fmt.Errorf("invalid digits %q: must be between 1 and 10", v)
+			}
+		case fieldStep:
+			var n int
+			n, err = strconv.Atoi(strings.TrimSuffix(v, "s"))
+			if err != nil || n < 1 {
+				err = fmt.Errorf("invalid time step %q", v)
+			}
+			row.Key.Period = time.Duration(n) * time.Second
+		}
+		if err != nil {
+			return Row{}, err
+		}
+	}
+	if len(row.Key.Secret) == 0 {
+		return Row{}, errors.New("seed is missing")
+	}
+	return row, nil
+}
+
+// decodeSeed decodes a seed; the error never includes the seed.
+//
+// Seeds in a column without an encoding are hex if they're valid hex, as that's
+// what most vendors use, and base32 otherwise.
+func decodeSeed(s string, f field) ([]byte, error) {
+	opt := otp.ParseSecretOptions{Encoding: otp.EncodingHex, AllowShortSecret: true}
+	switch f {
+	case fieldSeedHex:
+		return otp.ParseSecretWith(s, opt)
+	case fieldSeed:
+		if b, err := otp.ParseSecretWith(s, opt); err == nil {
+			return b, nil
+		}
+	}
+	opt.Encoding = otp.EncodingBase32
+	return otp.ParseSecretWith(s, opt)
+}
+
+// delimiter detects the delimiter from the first line.
+func delimiter(data []byte) rune {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.ContainsRune(first, ',') {
+		return ','
+	}
+	if bytes.ContainsRune(first, ';') {
+		return ';'
+	}
+	if bytes.ContainsRune(first, '\t') {
+		return '\t'
+	}
+	return ','
+}
+
+// Write rows as a CSV seed file with the given layout.
+//
+// Only TOTP keys are supported.
+func Write(w io.Writer, rows []Row, l Layout) error {
+	fields := make([]field, len(l.Header))
+	for i, h := range l.Header {
+		fields[i] = columns[strings.ToLower(strings.TrimSpace(h))]
+		if fields[i] == fieldNone {
+			return fmt.Errorf("csvseed.Write: unknown column %q", h)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	err := cw.Write(l.Header)
+	if err != nil {
+		return fmt.Errorf("csvseed.Write: %w", err)
+	}
+	rec := make([]string, len(fields))
+	for i, row := range rows {
+		err := formatRow(rec, fields, row, l.Base32)
+		if err != nil {
+			return fmt.Errorf("csvseed.Write: row %d: %w", i, err)
+		}
+		err = cw.Write(rec)
+		if err != nil {
+			return fmt.Errorf("csvseed.Write: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("csvseed.Write: %w", err)
+	}
+	return nil
+}
+
+func formatRow(rec []string, fields []field, row Row, b32 bool) error {
+	var err error
+	row.Key, err = row.Key.Normalize()
+	if err != nil {
+		return err
+	}
+	if row.Key.Kind != otp.KindTOTP {
+		return fmt.Errorf("kind %q not supported", row.Key.Kind)
+	}
+	if !row.Key.T0.IsZero() {
+		return errors.New("T0 not supported")
+	}
+	alg, err := otp.FormatAlgorithm(row.Key.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	for i, f := range fields {
+		switch f {
+		case fieldSerial:
+			rec[i] = row.Serial
+		case fieldAccount:
+			rec[i] = row.Key.Account
+		case fieldManufacturer:
+			rec[i] = row.Manufacturer
+		case fieldModel:
+			rec[i] = row.Model
+		case fieldSeed, fieldSeedHex, fieldSeedBase32:
+			if f == fieldSeedBase32 || (f == fieldSeed && b32) {
+				rec[i] = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(row.Key.Secret)
+			} else {
+				rec[i] = hex.EncodeToString(row.Key.Secret)
+			}
+		case fieldAlgorithm:
+			rec[i] = alg
+		case fieldDigits:
+			rec[i] = strconv.Itoa(row.Key.Digits)
+		case fieldStep:
+			rec[i] = strconv.FormatInt(int64(row.Key.Period/time.Second), 10)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package csvseed_test
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/csvseed"
+)
+
+var secret = []byte("12345678901234567890")
+
+func TestRead(t *testing.T) {
+	row := func(line int, serial string, digits int, period time.Duration, alg crypto.Hash) csvseed.Row {
+		return csvseed.Row{Line: line, Serial: serial, Key: otp.Key{Kind: otp.KindTOTP, Secret: secret,
+			Algorithm: alg, Digits: digits, Period: period}}
+	}
+
+	tests := []struct {
+		in      string
+		want    []csvseed.Row
+		wantErr string
+	}{
+		{"serial,seed,algorithm,digits,time step\n" +
+			"1001,3132333435363738393031323334353637383930,SHA1,6,30\n" +
+			"1002,GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ,sha256,8,60\n",
+			[]csvseed.Row{
+				row(2, "1001", 6, 30*time.Second, crypto.SHA1),
+				row(3, "1002", 8, time.Minute, crypto.SHA256)}, ""},
+		// Feitian style: semicolons, hex seed, no algorithm.
+		{"SN;Seed (hex);Length;Step\r\n1001;3132333435363738393031323334353637383930;6;60\r\n",
+			[]csvseed.Row{row(2, "1001", 6, time.Minute, crypto.SHA1)}, ""},
+		// Azure/Token2 style.
+		{"\ufeffupn,serial number,secret key,time interval,manufacturer,model\n" +
+			"alice@example.com,1001,GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ,30,Token2,C202\n",
+			[]csvseed.Row{{Line: 2, Serial: "1001", Manufacturer: "Token2", Model: "C202",
+				Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, Account: "alice@example.com",
+					Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}}}, ""},
+		// Only a seed, unknown columns, and an empty line.
+		{"seed\tcomment\n\n3132333435363738393031323334353637383930\tx\n",
+			[]csvseed.Row{row(3, "", 6, 30*time.Second, crypto.SHA1)}, ""},
+
+		{"", nil, "file is empty"},
+		{"serial,digits\n1,6\n", nil, "no seed column"},
+		{"serial,seed,algorithm,digits,time step\n" +
+			"1001,3132333435363738393031323334353637383930,SHA1,6,30\n" +
+			"1002,,SHA1,6,30\n" +
+			"1003,GEZD1,SHA1,6,30\n" +
+			"1004,3132333435363738393031323334353637383930,MD5,6,30\n" +
+			"1005,3132333435363738393031323334353637383930,SHA1,11,30\n" +
+			"1006,3132333435363738393031323334353637383930,SHA1,6,-1\n",
+			[]csvseed.Row{row(2, "1001", 6, 30*time.Second, crypto.SHA1)},
+			"line 3: seed is missing\nline 4: invalid seed: otp.ParseSecret: invalid character '1' at position 4 for base32\n" +
+				"line 5: unsupported algorithm \"MD5\"\nline 6: invalid digits \"11\": must be between 1 and 10\n" +
+				"line 7: invalid time step \"-1\""},
+		{"hex seed\nGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ\n", nil, "line 2: invalid seed: otp.ParseSecret: invalid character 'G' at position 0 for hex"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := csvseed.Read(strings.NewReader(tt.in))
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+			if err != nil && strings.Contains(err.Error(), "GEZD") {
+				t.Errorf("error contains secret: %s", err)
+			}
+		})
+	}
+}
+
+func TestReadRowError(t *testing.T) {
+	_, err := csvseed.Read(strings.NewReader("serial,seed\n1,\n2,\n"))
+	var rowErr csvseed.RowError
+	if !errors.As(err, &rowErr) {
+		t.Fatalf("not a RowError: %#v", err)
+	}
+	if rowErr.Line != 2 {
+		t.Errorf("line %d", rowErr.Line)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rows := []csvseed.Row{
+		{Serial: "1001", Key: otp.Key{Secret: secret}},
+		{Serial: "1002", Manufacturer: "Token2", Model: "C202", Key: otp.Key{Kind: otp.KindTOTP, Secret: secret,
+			Account: "alice@example.com", Algorithm: crypto.SHA256, Digits: 8, Period: time.Minute}},
+	}
+
+	tests := []struct {
+		layout csvseed.Layout
+		want   string
+	}{
+		{csvseed.Generic, "serial,seed,algorithm,digits,time step\n" +
+			"1001,3132333435363738393031323334353637383930,SHA1,6,30\n" +
+			"1002,3132333435363738393031323334353637383930,SHA256,8,60\n"},
+		{csvseed.Azure, "upn,serial number,secret key,time interval,manufacturer,model\n" +
+			",1001,GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ,30,,\n" +
+			"alice@example.com,1002,GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ,60,Token2,C202\n"},
+		{csvseed.Layout{Header: []string{"sn", "seed"}, Base32: true}, "sn,seed\n" +
+			"1001,GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ\n" +
+			"1002,GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := csvseed.Write(buf, rows, tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := buf.String(); have != tt.want {
+				t.Errorf("\nhave:\n%s\nwant:\n%s", have, tt.want)
+			}
+
+			read, err := csvseed.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(read) != len(rows) {
+				t.Fatalf("read %d rows", len(read))
+			}
+			for i, r := range read {
+				if r.Serial != rows[i].Serial || string(r.Key.Secret) != string(rows[i].Key.Secret) {
+					t.Errorf("round-trip row %d:\nhave: %#v\nwant: %#v", i, r, rows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		layout  csvseed.Layout
+		row     csvseed.Row
+		wantErr string
+	}{
+		{csvseed.Layout{Header: []string{"seed", "color"}}, csvseed.Row{Key: otp.Key{Secret: secret}}, `unknown column "color"`},
+		{csvseed.Generic, csvseed.Row{}, "secret is empty"},
+		{csvseed.Generic, csvseed.Row{Key: otp.Key{Kind: otp.KindHOTP, Secret: secret}}, `kind "hotp" not supported`},
+		{csvseed.Generic, csvseed.Row{Key: otp.Key{Secret: secret, T0: time.Unix(10, 0)}}, "T0 not supported"},
+		{csvseed.Generic, csvseed.Row{Key: otp.Key{Secret: secret, Algorithm: crypto.MD5}}, "algorithm MD5 not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			err := csvseed.Write(new(bytes.Buffer), []csvseed.Row{tt.row}, tt.layout)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	rows, err := csvseed.Read(strings.NewReader("serial,seed,digits\n1001,3132333435363738393031323334353637383930,8\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rows[0].Key.Generator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := g.TokenAt(time.Unix(59, 0)); err != nil || have != "94287082" {
+		t.Errorf("have %q", have)
+	}
+}
+
+func errorContains(err error, want string) bool {
+	if err == nil {
+		return want == ""
+	}
+	if want == "" {
+		return false
+	}
+	return strings.Contains(err.Error(), want)
+}
@@ -0,0 +1,163 @@
+package otp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TokenEncoder encodes the value from the HMAC as a token.
+type TokenEncoder interface {
+	// Encode the 31-bit value v as a token of length characters.
+	Encode(v uint32, length int) string
+
+	// Normalize a token entered by a user so it can be compared to a token
+	// from Encode(), e.g. by changing the case. It returns false if the token
+	// can never be valid.
+	Normalize(token string, length int) (string, bool)
+}
+
+// Decimal encodes tokens as zero-padded decimal digits, as described in
+// RFC4226. This is the default.
+func Decimal() TokenEncoder { return decimalEncoder{} }
+
+// Hex encodes tokens as lower-case hexadecimal characters.
+func Hex() TokenEncoder {
+	return alphabetEncoder{chars: "0123456789abcdef", fold: strings.ToLower}
+}
+
+// Steam encodes tokens in the alphabet used by Steam Guard. Unlike Alphabet(),
+// the least significant character comes first.
+func Steam() TokenEncoder {
+	return alphabetEncoder{chars: "23456789BCDFGHJKMNPQRTVWXY", reverse: true, fold: strings.ToUpper}
+}
+
+type decimalEncoder struct{}
+
+func (decimalEncoder) Encode(v uint32, length int) string { return decimal(int(v), length) }
+
+func (decimalEncoder) Normalize(token string, length int) (string, bool) {
+	if len(token) != length {
+		return token, false
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return token, false
+		}
+	}
+	return token, true
+}
+
+type alphabetEncoder struct {
+	chars   string
+	reverse bool
+	fold    func(string) string
+}
+
+// Alphabet encodes tokens with a custom alphabet; for example
+// Alphabet("0123456789") is identical to Decimal(), and Alphabet("01") encodes
+// tokens as binary.
+//
+// Larger alphabets give more possible tokens for the same length. Note the
+// value from the HMAC is 31 bits, so tokens have at most 31 bits of entropy
+// regardless of the alphabet and length.
+//
+// If all letters in the alphabet are the same case then tokens are accepted in
+// either case. Panics if chars has fewer than two characters, has duplicate
+// characters, or isn't ASCII.
+func Alphabet(chars string) TokenEncoder {
+	if len(chars) < 2 {
+		panic("otp.Alphabet: must have at least two characters")
+	}
+	var upper, lower bool
+	for i, c := range chars {
+		if c > unicode.MaxASCII {
+			panic("otp.Alphabet: must be ASCII")
+		}
+		if strings.IndexRune(chars[i+1:], c) > -1 {
+			panic("otp.Alphabet: duplicate character " + string(c))
+		}
+		upper = upper || unicode.IsUpper(c)
+		lower = lower || unicode.IsLower(c)
+	}
+
+	a := alphabetEncoder{chars: chars}
+	switch {
+	case upper && !lower:
+		a.fold = strings.ToUpper
+	case lower && !upper:
+		a.fold = strings.ToLower
+	}
+	return a
+}
+
+func (a alphabetEncoder) Encode(v uint32, length int) string {
+	var (
+		b = make([]byte, length)
+		n = uint32(len(a.chars))
+	)
+	for i := range b {
+		if !a.reverse {
+			i = length - i - 1
+		}
+		b[i] = a.chars[v%n]
+		v /= n
+	}
+	return string(b)
+}
+
+func (a alphabetEncoder) Normalize(token string, length int) (string, bool) {
+	if a.fold != nil {
+		token = a.fold(token)
+	}
+	if len(token) != length {
+		return token, false
+	}
+	for _, c := range token {
+		if !strings.ContainsRune(a.chars, c) {
+			return token, false
+		}
+	}
+	return token, true
+}
+
+type groupedEncoder struct {
+	enc  TokenEncoder
+	size int
+	sep  string
+}
+
+// Grouped splits tokens from enc in groups of size characters separated by
+// sep, for example "123 456" or "1234-5678".
+//
+// Tokens are accepted with or without separators, and spaces are ignored.
+// Panics if size is lower than 1.
+func Grouped(enc TokenEncoder, size int, sep string) TokenEncoder {
+	if size < 1 {
+		panic("otp.Grouped: size must be greater than 0")
+	}
+	return groupedEncoder{enc: enc, size: size, sep: sep}
+}
+
+func (g groupedEncoder) Encode(v uint32, length int) string {
+	return g.group(g.enc.Encode(v, length))
+}
+
+func (g groupedEncoder) Normalize(token string, length int) (string, bool) {
+	token = strings.ReplaceAll(token, " ", "")
+	if g.sep != "" {
+		token = strings.ReplaceAll(token, g.sep, "")
+	}
+	token, ok := g.enc.Normalize(token, length)
+	return g.group(token), ok
+}
+
+func (g groupedEncoder) group(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i += g.size {
+		if i > 0 {
+			b.WriteString(g.sep)
+		}
+		b.WriteString(s[i:min(i+g.size, len(s))])
+	}
+	return b.String()
+}
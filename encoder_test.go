@@ -0,0 +1,106 @@
+package otp_test
+
+import (
+	"crypto/sha1"
+	"errors"
+	"testing"
+
+	"zgo.at/otp"
+)
+
+func TestTokenEncoder(t *testing.T) {
+	tests := []struct {
+		enc    otp.TokenEncoder
+		length int
+		want   []string // RFC 4226 counters 0 and 1
+		accept []string
+		reject []string
+	}{
+		{otp.Decimal(), 6, []string{"755224", "287082"},
+			nil, []string{"75522", "7552244", "75522a", "755 224"}},
+		{otp.Alphabet("0123456789"), 6, []string{"755224", "287082"},
+			nil, []string{"75522", "75522a"}},
+		{otp.Hex(), 8, []string{"4c93cf18", "41397eea"},
+			[]string{"4C93CF18"}, []string{"4c93cf1", "4c93cf1g"}},
+		{otp.Alphabet("ABCDEFGHJKLMNPQRSTUVWXYZ23456789"), 6, []string{"GKHV22", "AVU9ZL"},
+			[]string{"gkhv22"}, []string{"GKHV21", "GKHV2"}},
+		{otp.Alphabet("01"), 8, []string{"00011000", "11101010"},
+			nil, []string{"00011002"}},
+		{otp.Alphabet("abcXYZ"), 4, []string{"cZaY", "bZcc"},
+			nil, []string{"czay", "CZAY"}},
+		{otp.Steam(), 5, []string{"GG5F5", "PV9M4"},
+			[]string{"gg5f5"}, []string{"GG5F", "GG5FA"}},
+		{otp.Grouped(otp.Decimal(), 3, " "), 6, []string{"755 224", "287 082"},
+			[]string{"755224", " 755  224 "}, []string{"755 22"}},
+		{otp.Grouped(otp.Decimal(), 4, "-"), 8, []string{"8475-5224", "9428-7082"},
+			[]string{"84755224", "8475 5224", "8-4-7-5-5-2-2-4"}, []string{"8475-522"}},
+		{otp.Grouped(otp.Hex(), 3, ""), 7, []string{"c93cf18", "1397eea"},
+			[]string{"C93CF18"}, []string{"c93cf1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			g, err := otp.NewGenerator(otp.GeneratorOptions{
+				Secret:  secret,
+				Length:  tt.length,
+				Encoder: tt.enc,
+				Counter: otp.HOTP(0),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tt.want {
+				if have := g.Token(i); have != want {
+					t.Errorf("Token(%d)\nhave: %q\nwant: %q", i, have, want)
+				}
+			}
+			for _, tok := range append(tt.accept, tt.want[0]) {
+				if !g.Verify(tok, 0) {
+					t.Errorf("rejected %q", tok)
+				}
+				if ok, err := g.VerifyStore(otp.NewMemoryCounterStore(), "user", tok, 0); !ok || err != nil {
+					t.Errorf("VerifyStore: rejected %q: %v", tok, err)
+				}
+				if _, err := g.Resync(tok, tt.want[1], 1); err != nil {
+					t.Errorf("Resync: rejected %q: %v", tok, err)
+				}
+			}
+			for _, tok := range tt.reject {
+				if _, err := g.VerifyDetailed(tok, 1); !errors.Is(err, otp.ErrInvalidFormat) {
+					t.Errorf("%q: wrong error: %v", tok, err)
+				}
+				if _, err := g.VerifyStore(otp.NewMemoryCounterStore(), "user", tok, 1); !errors.Is(err, otp.ErrInvalidFormat) {
+					t.Errorf("VerifyStore: %q: wrong error: %v", tok, err)
+				}
+			}
+			if g.Verify(tt.want[1], 0) {
+				t.Errorf("accepted %q", tt.want[1])
+			}
+		})
+	}
+
+	// Default is unchanged.
+	if have := otp.New(secret, 6, sha1.New, otp.HOTP(0)).Token(0); have != "755224" {
+		t.Errorf("have %q", have)
+	}
+}
+
+func TestAlphabetPanic(t *testing.T) {
+	tests := []struct {
+		want string
+		f    func()
+	}{
+		{"otp.Alphabet: must have at least two characters", func() { otp.Alphabet("a") }},
+		{"otp.Alphabet: duplicate character a", func() { otp.Alphabet("abca") }},
+		{"otp.Alphabet: must be ASCII", func() { otp.Alphabet("abcé") }},
+		{"otp.Grouped: size must be greater than 0", func() { otp.Grouped(otp.Decimal(), 0, " ") }},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			defer wantPanic(t, tt.want)
+			tt.f()
+		})
+	}
+}
@@ -3,10 +3,12 @@
 package main
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 
 	"zgo.at/otp"
 )
@@ -17,7 +19,13 @@ type User struct {
 	TOTPSecret []byte
 }
 
-var userStore = make(map[int]*User)
+var (
+	userStore = make(map[int]*User)
+
+	// The KEK would normally be loaded from a secrets manager or environment
+	// variable, and never stored in the database.
+	sealer *otp.Sealer
+)
 
 func findUser(id int) *User {
 	// Normally this would be e.g. "select * from users where id = ?
@@ -30,18 +38,36 @@ func findUser(id int) *User {
 
 func (u *User) setSecret(secret []byte) {
 	// Normally this wouild be e.g. update users set totp_secret = ? where id = ?
-	u.TOTPSecret = secret
+	//
+	// The secret is encrypted before storing it, so that leaking the database
+	// doesn't leak all the secrets. The user ID is used as associated data, so
+	// the sealed secret can't be copied to another user.
+	u.TOTPSecret = sealer.Seal(secret, strconv.Itoa(u.ID))
+}
+
+func (u *User) secret() (otp.SecretKey, error) {
+	return sealer.Open(u.TOTPSecret, strconv.Itoa(u.ID))
 }
 
 func main() {
+	kek := make([]byte, 32)
+	_, _ = rand.Read(kek)
+	var err error
+	sealer, err = otp.NewSealer(1, map[uint32][]byte{1: kek})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// Store shared secret for this user in the database, e.g.
 		user := findUser(1)
-		user.setSecret(otp.Secret())
+		secret := otp.Secret()
+		user.setSecret(secret)
 
 		// Generate URL that authenticator apps can pick up on.
-		url := otp.URL(user.TOTPSecret, "example.com", user.Email)
+		url := otp.URL(secret, "example.com", user.Email)
 		png, err := url.PNGDataURL(200)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
@@ -53,13 +79,14 @@ func main() {
 		fmt.Fprintf(w, `
 			<h1>Activate OTP</h1>
 			<p>Secret: <code>%s</code></p>
+			<p>URL: <code>%s</code></p>
 			<img src="%s">
 
 			<form method="POST" action="verify">
 				<input type="text" name="token">
 				<button>Verify</button>
 			</form>
-		`, url.String(), png)
+		`, otp.FormatSecret(secret, otp.FormatSecretOptions{Group: 4}), url.String(), png)
 	})
 
 	mux.HandleFunc("/verify", func(w http.ResponseWriter, r *http.Request) {
@@ -78,8 +105,14 @@ func main() {
 		// Find our user, should have sharedsecret set.
 		user := findUser(1)
 
-		// Verify token.
-		o := otp.New(user.TOTPSecret, 6, sha1.New, otp.TOTP(0, nil))
+		// Decrypt the secret and verify token.
+		secret, err := user.secret()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		defer secret.Destroy()
+		o := otp.NewTOTP(secret, 6, sha1.New, otp.TOTPConfig{})
 		if !o.Verify(token, 1) {
 			http.Error(w, "error: invalid token", 400)
 			return
@@ -88,7 +121,7 @@ func main() {
 	})
 
 	fmt.Println("listening on localhost:3000")
-	err := http.ListenAndServe("localhost:3000", mux)
+	err = http.ListenAndServe("localhost:3000", mux)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
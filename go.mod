@@ -1,3 +1,3 @@
 module zgo.at/otp
 
-go 1.22
+go 1.24
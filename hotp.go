@@ -0,0 +1,246 @@
+package otp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// HOTP returns a counter function to generate HOTP tokens as defined in
+// RFC4226.
+//
+// HOTP tokens are counter-based; counter should be the next counter for which
+// a token is expected. Use VerifyStore() to verify tokens and advance the
+// counter.
+func HOTP(counter uint64) CounterFunc {
+	return func(offset int) uint64 {
+		return counter + uint64(offset)
+	}
+}
+
+// CounterStore stores HOTP counters.
+type CounterStore interface {
+	// Counter gets the current counter for key, or 0 if there is no counter
+	// for this key yet.
+	Counter(key string) (uint64, error)
+
+	// SetCounter sets the counter for key to new, but only if the current
+	// value is still old. It returns false if the counter was changed in the
+	// meantime.
+	SetCounter(key string, old, new uint64) (bool, error)
+}
+
+// VerifyStore verifies a HOTP token against the counter stored for key.
+//
+// This accepts tokens from the current counter up to counter+window. On success
+// the counter is advanced to one past the counter that matched, so that the
+// same token can't be used twice. The CounterFunc the generator was created
+// with isn't used.
+//
+// Returns false if the token doesn't match, or ErrInvalidFormat if the token can
+// never be valid. Returns ErrReplayed if the token matched but the counter was
+// advanced by another call in the meantime.
+func (g Generator) VerifyStore(store CounterStore, key, token string, window int) (bool, error) {
+	token, ok := g.encoder().Normalize(token, g.length)
+	if !ok {
+		return false, ErrInvalidFormat
+	}
+	c, err := store.Counter(key)
+	if err != nil {
+		return false, err
+	}
+
+	g.counter = HOTP(c)
+	i, ok := g.match(token, 0, window)
+	if !ok {
+		return false, nil
+	}
+	ok, err = store.SetCounter(key, c, c+uint64(i)+1)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, ErrReplayed
+	}
+	return true, nil
+}
+
+// ErrResync is returned by Resync() if no consecutive tokens were found.
+var ErrResync = errors.New("otp: no matching consecutive tokens found")
+
+// ResyncResult is the result of a successful Resync().
+type ResyncResult struct {
+	// Offset of the second token, relative to the current counter.
+	Offset int
+
+	// Counter is the new counter: one past the counter of the second token.
+	// This should be stored as the next expected counter.
+	Counter uint64
+}
+
+// Resync resynchronizes the counter as described in RFC4226 section 7.4.
+//
+// This can be used when the counter on the client has drifted far ahead of the
+// server. The user enters two consecutive tokens, and the counters from offset 0
+// up to window are searched for two adjacent tokens that match.
+//
+// The window should be considerably larger than the window used with Verify(),
+// for example 1000. Returns ErrResync if the tokens weren't found, or
+// ErrInvalidFormat if either token can never be valid.
+func (g Generator) Resync(token1, token2 string, window int) (ResyncResult, error) {
+	token1, ok1 := g.encoder().Normalize(token1, g.length)
+	token2, ok2 := g.encoder().Normalize(token2, g.length)
+	if !ok1 || !ok2 {
+		return ResyncResult{}, ErrInvalidFormat
+	}
+
+	var (
+		t1, t2     = []byte(token1), []byte(token2)
+		next       = []byte(g.Token(0))
+		found, off int
+	)
+	for i := 1; i <= window; i++ {
+		cur := next
+		next = []byte(g.Token(i))
+		eq := subtle.ConstantTimeCompare(t1, cur) & subtle.ConstantTimeCompare(t2, next)
+		off = subtle.ConstantTimeSelect(eq&^found, i, off)
+		found |= eq
+	}
+	if found == 0 {
+		return ResyncResult{}, ErrResync
+	}
+	return ResyncResult{Offset: off, Counter: g.counter(off) + 1}, nil
+}
+
+// MemoryCounterStore stores HOTP counters in memory.
+type MemoryCounterStore struct {
+	mu sync.Mutex
+	c  map[string]uint64
+}
+
+var _ CounterStore = &MemoryCounterStore{}
+
+// NewMemoryCounterStore creates a new in-memory counter store.
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{c: make(map[string]uint64)}
+}
+
+func (m *MemoryCounterStore) Counter(key string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.c[key], nil
+}
+
+func (m *MemoryCounterStore) SetCounter(key string, old, new uint64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.c[key] != old {
+		return false, nil
+	}
+	m.c[key] = new
+	return true, nil
+}
+
+// FileCounterStore stores HOTP counters in a JSON file.
+//
+// Updates are written to a temporary file which is then renamed, so the file is
+// never left in a partially written state.
+//
+// Access is only serialized within this process. The file isn't locked, so if
+// several processes (e.g. several server instances) use the same file then
+// concurrent logins can accept the same HOTP counter twice. Use a CounterStore
+// backed by a database in that case, with SetCounter() as a conditional update
+// such as:
+//
+//	update counters set counter = ? where key = ? and counter = ?
+//
+// and return true if one row was changed.
+type FileCounterStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+var _ CounterStore = &FileCounterStore{}
+
+// NewFileCounterStore creates a new counter store backed by the file at path.
+//
+// The file is created on the first write if it doesn't exist yet.
+func NewFileCounterStore(path string) *FileCounterStore {
+	return &FileCounterStore{path: path}
+}
+
+func (f *FileCounterStore) Counter(key string) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.read()
+	if err != nil {
+		return 0, err
+	}
+	return c[key], nil
+}
+
+func (f *FileCounterStore) SetCounter(key string, old, new uint64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.read()
+	if err != nil {
+		return false, err
+	}
+	if c[key] != old {
+		return false, nil
+	}
+	c[key] = new
+	return true, f.write(c)
+}
+
+func (f *FileCounterStore) read() (map[string]uint64, error) {
+	c := make(map[string]uint64)
+	d, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return nil, err
+	}
+	if len(d) == 0 {
+		return c, nil
+	}
+	err = json.Unmarshal(d, &c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (f *FileCounterStore) write(c map[string]uint64) error {
+	d, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(d)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
@@ -0,0 +1,159 @@
+package otp_test
+
+import (
+	"crypto/sha1"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"zgo.at/otp"
+)
+
+func TestHOTP(t *testing.T) {
+	// See RFC 4226 Appendix D
+	tests := []string{"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489"}
+
+	for i, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have := otp.New(secret, 6, sha1.New, otp.HOTP(uint64(i))).Token(0)
+			if have != tt {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt)
+			}
+			have = otp.New(secret, 6, sha1.New, otp.HOTP(0)).Token(i)
+			if have != tt {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt)
+			}
+		})
+	}
+}
+
+func TestVerifyStore(t *testing.T) {
+	stores := map[string]otp.CounterStore{
+		"memory": otp.NewMemoryCounterStore(),
+		"file":   otp.NewFileCounterStore(filepath.Join(t.TempDir(), "counters.json")),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			o := otp.New(secret, 6, sha1.New, otp.HOTP(0))
+
+			verify := func(token string, window int, want bool, wantCounter uint64) {
+				t.Helper()
+				ok, err := o.VerifyStore(store, "user", token, window)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != want {
+					t.Errorf("VerifyStore(%q, %d): have %t; want %t", token, window, ok, want)
+				}
+				c, err := store.Counter("user")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if c != wantCounter {
+					t.Errorf("counter: have %d; want %d", c, wantCounter)
+				}
+			}
+
+			verify("755224", 0, true, 1)  // Counter 0
+			verify("755224", 5, false, 1) // Already used.
+			verify("969429", 0, false, 1) // Counter 3; outside window.
+			verify("969429", 2, true, 4)
+			verify("287082", 10, false, 4) // Counter 1; in the past.
+			verify("520489", 5, true, 10)  // Counter 9
+
+			if _, err := o.VerifyStore(store, "user", "XXXXXX", 5); !errors.Is(err, otp.ErrInvalidFormat) {
+				t.Errorf("wrong error: %v", err)
+			}
+
+			c, err := store.Counter("other")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c != 0 {
+				t.Errorf("counter for other: %d", c)
+			}
+		})
+	}
+
+	t.Run("reopen file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "counters.json")
+		ok, err := otp.New(secret, 6, sha1.New, otp.HOTP(0)).
+			VerifyStore(otp.NewFileCounterStore(path), "user", "359152", 5)
+		if err != nil || !ok {
+			t.Fatal(ok, err)
+		}
+
+		c, err := otp.NewFileCounterStore(path).Counter("user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != 3 {
+			t.Errorf("counter: %d", c)
+		}
+	})
+}
+
+func TestVerifyStoreConcurrent(t *testing.T) {
+	var (
+		store = otp.NewMemoryCounterStore()
+		o     = otp.New(secret, 6, sha1.New, otp.HOTP(0))
+		wg    sync.WaitGroup
+		n     atomic.Int32
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := o.VerifyStore(store, "user", "755224", 5)
+			if err != nil && !errors.Is(err, otp.ErrReplayed) {
+				t.Error(err)
+			}
+			if ok {
+				n.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n.Load() != 1 {
+		t.Errorf("token accepted %d times", n.Load())
+	}
+}
+
+func TestResync(t *testing.T) {
+	tests := []struct {
+		counter        uint64
+		token1, token2 string
+		window         int
+		want           otp.ResyncResult
+		wantErr        error
+	}{
+		{0, "755224", "287082", 10, otp.ResyncResult{Offset: 1, Counter: 2}, nil},
+		{0, "287922", "162583", 10, otp.ResyncResult{Offset: 7, Counter: 8}, nil},
+		{2, "287922", "162583", 10, otp.ResyncResult{Offset: 5, Counter: 8}, nil},
+		{0, "399871", "520489", 9, otp.ResyncResult{Offset: 9, Counter: 10}, nil},
+
+		{0, "399871", "520489", 8, otp.ResyncResult{}, otp.ErrResync},  // Outside window.
+		{0, "287082", "755224", 10, otp.ResyncResult{}, otp.ErrResync}, // Wrong order.
+		{0, "755224", "359152", 10, otp.ResyncResult{}, otp.ErrResync}, // Not consecutive.
+		{5, "755224", "287082", 10, otp.ResyncResult{}, otp.ErrResync}, // In the past.
+		{0, "755224", "XXXXXX", 10, otp.ResyncResult{}, otp.ErrInvalidFormat},
+		{0, "75522", "287082", 10, otp.ResyncResult{}, otp.ErrInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := otp.New(secret, 6, sha1.New, otp.HOTP(tt.counter)).Resync(tt.token1, tt.token2, tt.window)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %+v\nwant: %+v", have, tt.want)
+			}
+		})
+	}
+}
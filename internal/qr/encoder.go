@@ -31,6 +31,13 @@ func Encode(level ErrorCorrectionLevel, content string) (*qrcode, error) {
 	return result, nil
 }
 
+// Fits reports if content fits in a QR code with the given level and at most
+// maxVersion, without encoding it.
+func Fits(level ErrorCorrectionLevel, maxVersion int, content string) bool {
+	vi := findSmallestVersionInfo(level, byteMode, len(content)*8)
+	return vi != nil && int(vi.Version) <= maxVersion
+}
+
 func newQR(dim int) *qrcode {
 	return &qrcode{
 		dimension: dim,
@@ -111,3 +111,21 @@ func Test_UnicodeEncoding(t *testing.T) {
 		t.Error("Unicode encoding should not be able to encode a 3kb string")
 	}
 }
+
+func Test_Fits(t *testing.T) {
+	if !Fits(M, 40, strings.Repeat("x", 2331)) {
+		t.Error("2331 bytes doesn't fit")
+	}
+	if Fits(M, 40, strings.Repeat("x", 2332)) {
+		t.Error("2332 bytes fits")
+	}
+	if !Fits(M, 25, strings.Repeat("x", 997)) {
+		t.Error("997 bytes doesn't fit in version 25")
+	}
+	if Fits(M, 25, strings.Repeat("x", 998)) {
+		t.Error("998 bytes fits in version 25")
+	}
+	if _, err := Encode(M, strings.Repeat("x", 2331)); err != nil {
+		t.Error(err)
+	}
+}
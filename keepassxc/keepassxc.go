@@ -0,0 +1,191 @@
+// Package keepassxc converts the TOTP attributes of KeePassXC entries.
+//
+// KeePassXC stores the TOTP configuration in the "otp" attribute as an
+// otpauth:// URL. Older versions and the KeePass TrayTOTP plugin use the
+// "TOTP Seed" and "TOTP Settings" attributes instead.
+package keepassxc
+
+import (
+	"crypto"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"zgo.at/otp"
+)
+
+// Names of the attributes with the TOTP configuration.
+const (
+	AttrOTP      = "otp"
+	AttrSeed     = "TOTP Seed"
+	AttrSettings = "TOTP Settings"
+)
+
+// Parse the TOTP configuration from the attributes of an entry. The "otp"
+// attribute is used if it's set, and "TOTP Seed" and "TOTP Settings" if it's
+// not.
+func Parse(attr map[string]string) (otp.Key, error) {
+	if v := attr[AttrOTP]; v != "" {
+		return ParseOTP(v)
+	}
+	if v := attr[AttrSeed]; v != "" {
+		return ParseLegacy(v, attr[AttrSettings])
+	}
+	return otp.Key{}, errors.New("keepassxc.Parse: no TOTP attributes")
+}
+
+// ParseOTP parses the "otp" attribute.
+//
+// This is usually an otpauth:// URL, but the KeeOTP format
+// ("key=...&size=6&step=30") is also accepted.
+//
+// Errors never include the attribute value, as it contains the secret.
+func ParseOTP(s string) (otp.Key, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 10 && strings.EqualFold(s[:10], "otpauth://") {
+		k, err := otp.ParseURL(s)
+		if err != nil {
+			return otp.Key{}, fmt.Errorf("keepassxc.ParseOTP: %w", err)
+		}
+		return k, nil
+	}
+
+	q, err := neturl.ParseQuery(s)
+	if err != nil || !q.Has("key") {
+		return otp.Key{}, errors.New("keepassxc.ParseOTP: not an otpauth:// URL or KeeOTP value")
+	}
+	k := otp.Key{Kind: otp.KindTOTP, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}
+	if k.Secret, err = decodeSecret(q.Get("key")); err != nil {
+		return otp.Key{}, fmt.Errorf("keepassxc.ParseOTP: %w", err)
+	}
+	if v := q.Get("size"); v != "" {
+		if k.Digits, err = parseDigits(v); err != nil {
+			return otp.Key{}, fmt.Errorf("keepassxc.ParseOTP: %w", err)
+		}
+	}
+	if v := q.Get("step"); v != "" {
+		if k.Period, err = parsePeriod(v); err != nil {
+			return otp.Key{}, fmt.Errorf("keepassxc.ParseOTP: %w", err)
+		}
+	}
+	if v := q.Get("otpHashMode"); v != "" {
+		if k.Algorithm, err = otp.ParseAlgorithm(v); err != nil {
+			return otp.Key{}, fmt.Errorf("keepassxc.ParseOTP: %w", err)
+		}
+	}
+	return k, nil
+}
+
+// ParseLegacy parses the "TOTP Seed" and "TOTP Settings" attributes.
+//
+// The settings are "period;digits", where digits can be "S" for Steam, and
+// optionally followed by ";algorithm". The default is "30;6" if settings is
+// empty.
+func ParseLegacy(seed, settings string) (otp.Key, error) {
+	k := otp.Key{Kind: otp.KindTOTP, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}
+
+	var err error
+	if k.Secret, err = decodeSecret(seed); err != nil {
+		return otp.Key{}, fmt.Errorf("keepassxc.ParseLegacy: %w", err)
+	}
+
+	settings = strings.TrimSpace(settings)
+	if settings == "" {
+		settings = "30;6"
+	}
+	parts := strings.Split(settings, ";")
+	if len(parts) > 3 {
+		return otp.Key{}, fmt.Errorf("keepassxc.ParseLegacy: invalid settings %q", settings)
+	}
+	if k.Period, err = parsePeriod(parts[0]); err != nil {
+		return otp.Key{}, fmt.Errorf("keepassxc.ParseLegacy: %w", err)
+	}
+	if len(parts) > 1 {
+		if strings.TrimSpace(parts[1]) == "S" {
+			k.Kind, k.Digits = otp.KindSteam, 5
+		} else if k.Digits, err = parseDigits(parts[1]); err != nil {
+			return otp.Key{}, fmt.Errorf("keepassxc.ParseLegacy: %w", err)
+		}
+	}
+	if len(parts) > 2 {
+		if k.Algorithm, err = otp.ParseAlgorithm(parts[2]); err != nil {
+			return otp.Key{}, fmt.Errorf("keepassxc.ParseLegacy: %w", err)
+		}
+	}
+	return k, nil
+}
+
+// FormatOTP formats the key for the "otp" attribute.
+func FormatOTP(k otp.Key) (string, error) {
+	if len(k.Secret) == 0 {
+		return "", errors.New("keepassxc.FormatOTP: secret is empty")
+	}
+	switch k.Kind {
+	case otp.KindTOTP, otp.KindSteam, "":
+		u, err := k.URL()
+		if err != nil {
+			return "", fmt.Errorf("keepassxc.FormatOTP: %w", err)
+		}
+		return u.String(), nil
+	default:
+		return "", fmt.Errorf("keepassxc.FormatOTP: kind %q not supported", k.Kind)
+	}
+}
+
+// FormatLegacy formats the key for the "TOTP Seed" and "TOTP Settings"
+// attributes.
+func FormatLegacy(k otp.Key) (seed, settings string, err error) {
+	k, err = k.Normalize()
+	if err != nil {
+		return "", "", fmt.Errorf("keepassxc.FormatLegacy: %w", err)
+	}
+	if !k.T0.IsZero() {
+		return "", "", errors.New("keepassxc.FormatLegacy: T0 not supported")
+	}
+	alg, err := otp.FormatAlgorithm(k.Algorithm)
+	if err != nil {
+		return "", "", fmt.Errorf("keepassxc.FormatLegacy: %w", err)
+	}
+
+	digits := strconv.Itoa(k.Digits)
+	switch k.Kind {
+	case otp.KindTOTP:
+	case otp.KindSteam:
+		if k.Digits != 5 {
+			return "", "", fmt.Errorf("keepassxc.FormatLegacy: %d digits not supported for Steam", k.Digits)
+		}
+		digits = "S"
+	default:
+		return "", "", fmt.Errorf("keepassxc.FormatLegacy: kind %q not supported", k.Kind)
+	}
+
+	settings = strconv.FormatInt(int64(k.Period/time.Second), 10) + ";" + digits
+	if alg != "SHA1" {
+		settings += ";" + alg
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret), settings, nil
+}
+
+func parsePeriod(s string) (time.Duration, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid period %q", s)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+func parseDigits(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 || n > 10 {
+		return 0, fmt.Errorf("invalid digits %q: must be between 1 and 10", s)
+	}
+	return n, nil
+}
+
+func decodeSecret(s string) ([]byte, error) {
+	return otp.ParseSecretWith(s, otp.ParseSecretOptions{Encoding: otp.EncodingBase32, AllowShortSecret: true})
+}
@@ -0,0 +1,137 @@
+package keepassxc_test
+
+import (
+	"crypto"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/keepassxc"
+)
+
+var secret = []byte("12345678901234567890")
+
+func TestParse(t *testing.T) {
+	totp := func(digits int, period time.Duration, alg crypto.Hash) otp.Key {
+		return otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: alg, Digits: digits, Period: period}
+	}
+	steam := otp.Key{Kind: otp.KindSteam, Secret: secret, Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}
+
+	tests := []struct {
+		in      map[string]string
+		want    otp.Key
+		wantErr string
+	}{
+		{map[string]string{"otp": "otpauth://totp/Example:alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&period=30&digits=6&issuer=Example"},
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: "alice",
+				Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ""},
+		{map[string]string{"otp": "otpauth://totp/Steam:alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&period=30&digits=5&issuer=Steam&encoder=steam"},
+			otp.Key{Kind: otp.KindSteam, Secret: secret, Issuer: "Steam", Account: "alice",
+				Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, ""},
+		{map[string]string{"otp": "key=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&size=8&step=60&otpHashMode=Sha256"},
+			totp(8, time.Minute, crypto.SHA256), ""},
+		{map[string]string{"otp": "key=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+			totp(6, 30*time.Second, crypto.SHA1), ""},
+
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+			totp(6, 30*time.Second, crypto.SHA1), ""},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "30;6"},
+			totp(6, 30*time.Second, crypto.SHA1), ""},
+		{map[string]string{"TOTP Seed": "gezd gnbv gy3t qojq gezd gnbv gy3t qojq", "TOTP Settings": "60;8"},
+			totp(8, time.Minute, crypto.SHA1), ""},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "30;S"},
+			steam, ""},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "30;7;SHA512"},
+			totp(7, 30*time.Second, crypto.SHA512), ""},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "45"},
+			totp(6, 45*time.Second, crypto.SHA1), ""},
+
+		{map[string]string{"Title": "x"}, otp.Key{}, "no TOTP attributes"},
+		{map[string]string{"otp": "hello"}, otp.Key{}, "not an otpauth:// URL or KeeOTP value"},
+		{map[string]string{"otp": "key=1"}, otp.Key{}, "otp.ParseSecret: invalid character '1'"},
+		{map[string]string{"otp": "key=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&size=11"}, otp.Key{}, `invalid digits "11"`},
+		{map[string]string{"otp": "key=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&otpHashMode=Md5"}, otp.Key{}, `unsupported algorithm "Md5"`},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "0;6"}, otp.Key{}, `invalid period "0"`},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "30;X"}, otp.Key{}, `invalid digits "X"`},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "30;6;SHA1;x"}, otp.Key{}, `invalid settings`},
+		{map[string]string{"TOTP Seed": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "TOTP Settings": "30;6;MD5"}, otp.Key{}, `unsupported algorithm "MD5"`},
+		{map[string]string{"TOTP Seed": "1234"}, otp.Key{}, "otp.ParseSecret: invalid character '1'"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := keepassxc.Parse(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		in                          otp.Key
+		wantOTP, wantSeed, wantSett string
+		wantErr                     string
+	}{
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: "alice"},
+			"otpauth://totp/Example:alice?issuer=Example&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "30;6", ""},
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret, Digits: 8, Period: time.Minute, Algorithm: crypto.SHA256},
+			"otpauth://totp/?algorithm=SHA256&digits=8&period=60&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "60;8;SHA256", ""},
+		{otp.Key{Kind: otp.KindSteam, Secret: secret},
+			"otpauth://totp/?digits=5&encoder=steam&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "30;S", ""},
+
+		{otp.Key{Kind: otp.KindHOTP, Secret: secret}, "", "", "", `kind "hotp" not supported`},
+		{otp.Key{}, "", "", "", "secret is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			haveOTP, err := keepassxc.FormatOTP(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			seed, sett, err := keepassxc.FormatLegacy(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if haveOTP != tt.wantOTP || seed != tt.wantSeed || sett != tt.wantSett {
+				t.Errorf("\nhave: %q %q %q\nwant: %q %q %q", haveOTP, seed, sett, tt.wantOTP, tt.wantSeed, tt.wantSett)
+			}
+			if err != nil {
+				return
+			}
+
+			k1, err := keepassxc.ParseOTP(haveOTP)
+			if err != nil {
+				t.Fatal(err)
+			}
+			k2, err := keepassxc.ParseLegacy(seed, sett)
+			if err != nil {
+				t.Fatal(err)
+			}
+			k1.Issuer, k1.Account = "", ""
+			if !reflect.DeepEqual(k1, k2) {
+				t.Errorf("round-trip different:\notp:    %#v\nlegacy: %#v", k1, k2)
+			}
+		})
+	}
+}
+
+func errorContains(err error, want string) bool {
+	if err == nil {
+		return want == ""
+	}
+	if want == "" {
+		return false
+	}
+	return strings.Contains(err.Error(), want)
+}
@@ -0,0 +1,559 @@
+package otp
+
+import (
+	"bytes"
+	"crypto"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of one-time password.
+type Kind string
+
+// Kinds of one-time passwords.
+const (
+	KindTOTP  Kind = "totp"
+	KindHOTP  Kind = "hotp"
+	KindSteam Kind = "steam"
+)
+
+// Key is a shared secret with all the parameters needed to generate tokens.
+type Key struct {
+	Kind      Kind
+	Secret    SecretKey
+	Issuer    string
+	Account   string
+	Algorithm crypto.Hash   // Hash function; SHA1, SHA256, or SHA512.
+	Digits    int           // Token length.
+	Period    time.Duration // Time step for TOTP and Steam.
+	T0        time.Time     // Start time for TOTP and Steam; zero value is the Unix epoch.
+	Counter   uint64        // Counter for HOTP.
+}
+
+var (
+	_ encoding.TextMarshaler   = Key{}
+	_ encoding.TextUnmarshaler = &Key{}
+	_ json.Marshaler           = Key{}
+	_ json.Unmarshaler         = &Key{}
+	_ driver.Valuer            = Key{}
+	_ sql.Scanner              = &Key{}
+	_ slog.LogValuer           = Key{}
+)
+
+var algorithms = map[string]crypto.Hash{
+	"SHA1":   crypto.SHA1,
+	"SHA256": crypto.SHA256,
+	"SHA512": crypto.SHA512,
+}
+
+// ParseAlgorithm parses a hash algorithm name: "SHA1", "SHA256", or "SHA512".
+// Case is ignored, and a dash is allowed (e.g. "sha-256").
+func ParseAlgorithm(s string) (crypto.Hash, error) {
+	a, ok := algorithms[strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(s)), "-", "")]
+	if !ok {
+		return 0, fmt.Errorf("otp.ParseAlgorithm: unsupported algorithm %q", s)
+	}
+	return a, nil
+}
+
+// FormatAlgorithm formats a hash algorithm as "SHA1", "SHA256", or "SHA512", as
+// used in otpauth:// URLs and most export formats. The zero value is formatted
+// as "SHA1".
+func FormatAlgorithm(a crypto.Hash) (string, error) {
+	switch a {
+	case 0, crypto.SHA1:
+		return "SHA1", nil
+	case crypto.SHA256:
+		return "SHA256", nil
+	case crypto.SHA512:
+		return "SHA512", nil
+	default:
+		return "", fmt.Errorf("otp.FormatAlgorithm: algorithm %s not supported", a)
+	}
+}
+
+// HashAlgorithm returns the algorithm for a hash function as accepted by New(),
+// for example crypto.SHA256 for sha256.New. This can be used to create a Key
+// with the same parameters as a generator.
+//
+// Returns an error if h isn't SHA1, SHA256, or SHA512.
+func HashAlgorithm(h func() hash.Hash) (crypto.Hash, error) {
+	// Functions can't be compared, so compare the hash of an empty input.
+	sum := h().Sum(nil)
+	for _, a := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA512} {
+		if bytes.Equal(sum, a.New().Sum(nil)) {
+			return a, nil
+		}
+	}
+	return 0, errors.New("otp.HashAlgorithm: not SHA1, SHA256, or SHA512")
+}
+
+// ParseURL parses an otpauth:// URL, as created by URL() and used by most OTP
+// apps.
+//
+// Parameters that are missing are set to their defaults: SHA1, 6 digits (5 for
+// Steam), and a period of 30 seconds. Both otpauth://steam/ and the
+// encoder=steam parameter are accepted for Steam keys.
+//
+// Errors never include the URL, as it contains the secret.
+func ParseURL(s string) (Key, error) {
+	errf := func(f string, a ...any) (Key, error) {
+		return Key{}, fmt.Errorf("otp.ParseURL: "+f, a...)
+	}
+
+	u, err := neturl.Parse(strings.TrimSpace(s))
+	if err != nil {
+		var uErr *neturl.Error
+		if errors.As(err, &uErr) {
+			err = uErr.Err // Don't include the URL.
+		}
+		return errf("%w", err)
+	}
+	if !strings.EqualFold(u.Scheme, "otpauth") {
+		return errf("scheme is %q and not otpauth", u.Scheme)
+	}
+
+	k := Key{Kind: Kind(strings.ToLower(u.Host)), Algorithm: crypto.SHA1, Digits: 6}
+	switch k.Kind {
+	case KindTOTP, KindHOTP:
+	case KindSteam:
+		k.Digits = 5
+	case "":
+		return errf("type is missing")
+	default:
+		return errf("unsupported type %q", u.Host)
+	}
+
+	q, err := neturl.ParseQuery(u.RawQuery)
+	if err != nil {
+		return errf("invalid query: %w", err)
+	}
+
+	// The issuer and account are separated by a colon. A colon that's part of
+	// the issuer or account must always be encoded, so an encoded colon is
+	// only accepted as the separator if what comes before it is the issuer
+	// parameter; otherwise it's part of the account.
+	label := strings.TrimPrefix(u.EscapedPath(), "/")
+	issuer, account, ok := strings.Cut(label, ":")
+	if ok {
+		if k.Issuer, err = neturl.PathUnescape(issuer); err != nil {
+			return errf("invalid issuer in label: %w", err)
+		}
+	} else {
+		account = label
+	}
+	if account, err = neturl.PathUnescape(account); err != nil {
+		return errf("invalid account in label: %w", err)
+	}
+	if iss := q.Get("issuer"); !ok && iss != "" {
+		if a, ok := strings.CutPrefix(account, iss+":"); ok {
+			k.Issuer, account = iss, a
+		}
+	}
+	k.Account = strings.TrimLeft(account, " ")
+
+	secret := q.Get("secret")
+	if secret == "" {
+		return errf("secret is missing")
+	}
+	if k.Secret, err = decodeBase32(secret); err != nil {
+		return errf("%w", err)
+	}
+
+	if q.Has("issuer") {
+		k.Issuer = q.Get("issuer")
+	}
+
+	if a := q.Get("algorithm"); a != "" {
+		if k.Algorithm, err = ParseAlgorithm(a); err != nil {
+			return errf("unsupported algorithm %q", a)
+		}
+	}
+
+	if d := q.Get("digits"); d != "" {
+		k.Digits, err = strconv.Atoi(d)
+		if err != nil || k.Digits < 1 || k.Digits > 10 {
+			return errf("invalid digits %q: must be between 1 and 10", d)
+		}
+	}
+
+	if e := q.Get("encoder"); e != "" {
+		if !strings.EqualFold(e, "steam") {
+			return errf("unsupported encoder %q", e)
+		}
+		if k.Kind == KindHOTP {
+			return errf("encoder %q can't be used with hotp", e)
+		}
+		if k.Kind != KindSteam && !q.Has("digits") {
+			k.Digits = 5
+		}
+		k.Kind = KindSteam
+	}
+
+	switch k.Kind {
+	case KindHOTP:
+		c := q.Get("counter")
+		if c == "" {
+			return errf("counter is required for hotp")
+		}
+		k.Counter, err = strconv.ParseUint(c, 10, 64)
+		if err != nil {
+			return errf("invalid counter %q", c)
+		}
+	default:
+		k.Period = 30 * time.Second
+		if p := q.Get("period"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				return errf("invalid period %q: must be a positive number of seconds", p)
+			}
+			k.Period = time.Duration(n) * time.Second
+		}
+		if t0 := q.Get("t0"); t0 != "" {
+			n, err := strconv.ParseInt(t0, 10, 64)
+			if err != nil {
+				return errf("invalid t0 %q: must be a Unix timestamp", t0)
+			}
+			k.T0 = time.Unix(n, 0).UTC()
+		}
+	}
+	return k, nil
+}
+
+// Normalize validates the key and returns a copy with missing parameters set to
+// their defaults: TOTP, SHA1, 6 digits (5 for Steam), and a period of 30
+// seconds for TOTP and Steam. A T0 at the Unix epoch is set to the zero value.
+//
+// This is useful for import and export formats that store every parameter.
+// The algorithm isn't checked; use FormatAlgorithm() for that.
+func (k Key) Normalize() (Key, error) {
+	k, err := k.normalize()
+	if err != nil {
+		return Key{}, fmt.Errorf("otp.Key.Normalize: %w", err)
+	}
+	return k, nil
+}
+
+func (k Key) normalize() (Key, error) {
+	errf := func(f string, a ...any) (Key, error) {
+		return Key{}, fmt.Errorf(f, a...)
+	}
+
+	if len(k.Secret) == 0 {
+		return errf("secret is empty")
+	}
+	switch k.Kind {
+	case KindTOTP, KindHOTP, KindSteam:
+	case "":
+		k.Kind = KindTOTP
+	default:
+		return errf("kind %q not supported", k.Kind)
+	}
+	if k.Algorithm == 0 {
+		k.Algorithm = crypto.SHA1
+	}
+	switch {
+	case k.Digits == 0 && k.Kind == KindSteam:
+		k.Digits = 5
+	case k.Digits == 0:
+		k.Digits = 6
+	case k.Digits < 1 || k.Digits > 10:
+		return errf("invalid digits %d: must be between 1 and 10", k.Digits)
+	}
+	if k.Kind != KindHOTP {
+		switch {
+		case k.Period == 0:
+			k.Period = 30 * time.Second
+		case k.Period < 0:
+			return errf("invalid period %s: must be positive", k.Period)
+		case k.Period%time.Second != 0:
+			return errf("period %s is not a whole number of seconds", k.Period)
+		}
+		if k.T0.Equal(time.Unix(0, 0)) {
+			k.T0 = time.Time{}
+		}
+	}
+	return k, nil
+}
+
+// Generator returns a generator for this key.
+//
+// Secrets shorter than 16 bytes are accepted, as existing keys often use
+// shorter secrets.
+func (k Key) Generator() (*Generator, error) {
+	opt := GeneratorOptions{Secret: k.Secret, AllowShortSecret: true, Length: k.Digits}
+	if k.Algorithm != 0 {
+		if !k.Algorithm.Available() {
+			return nil, fmt.Errorf("otp.Key.Generator: hash algorithm %s is not available", k.Algorithm)
+		}
+		opt.Hash = k.Algorithm.New
+	}
+
+	switch k.Kind {
+	case KindTOTP, "":
+		opt.Period, opt.T0 = k.Period, k.T0
+	case KindHOTP:
+		opt.Counter = HOTP(k.Counter)
+	case KindSteam:
+		opt.Period, opt.T0, opt.Encoder = k.Period, k.T0, Steam()
+		if opt.Length == 0 {
+			opt.Length = 5
+		}
+	default:
+		return nil, fmt.Errorf("otp.Key.Generator: unknown kind %q", k.Kind)
+	}
+	return NewGenerator(opt)
+}
+
+// MarshalText encodes the key as an otpauth:// URL.
+func (k Key) MarshalText() ([]byte, error) {
+	u, err := k.URL()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText decodes an otpauth:// URL with ParseURL().
+func (k *Key) UnmarshalText(text []byte) error {
+	kk, err := ParseURL(string(text))
+	if err != nil {
+		return err
+	}
+	*k = kk
+	return nil
+}
+
+type keyJSON struct {
+	Kind      Kind   `json:"kind"`
+	Secret    string `json:"secret"`
+	Issuer    string `json:"issuer,omitempty"`
+	Account   string `json:"account,omitempty"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int64  `json:"period,omitempty"`
+	T0        int64  `json:"t0,omitempty"`
+	Counter   uint64 `json:"counter,omitempty"`
+}
+
+// MarshalJSON encodes the key as a JSON object, with the secret as base32, the
+// period in seconds, and T0 as a Unix timestamp.
+func (k Key) MarshalJSON() ([]byte, error) {
+	if k.Period%time.Second != 0 {
+		return nil, fmt.Errorf("otp.Key.MarshalJSON: period %s is not a whole number of seconds", k.Period)
+	}
+	j := keyJSON{
+		Kind:    k.Kind,
+		Secret:  base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret),
+		Issuer:  k.Issuer,
+		Account: k.Account,
+		Digits:  k.Digits,
+		Period:  int64(k.Period / time.Second),
+		Counter: k.Counter,
+	}
+	if j.Kind == "" {
+		j.Kind = KindTOTP
+	}
+	var err error
+	if j.Algorithm, err = FormatAlgorithm(k.Algorithm); err != nil {
+		return nil, fmt.Errorf("otp.Key.MarshalJSON: algorithm %s not supported", k.Algorithm)
+	}
+	if !k.T0.IsZero() {
+		j.T0 = k.T0.Unix()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a key from a JSON object as created by MarshalJSON(),
+// or from a string with an otpauth:// URL. Missing parameters are set to their
+// defaults, like ParseURL(). A JSON null leaves the key unchanged.
+func (k *Key) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+		return k.UnmarshalText([]byte(s))
+	}
+
+	var j keyJSON
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return err
+	}
+
+	kk := Key{
+		Kind:      j.Kind,
+		Issuer:    j.Issuer,
+		Account:   j.Account,
+		Algorithm: crypto.SHA1,
+		Digits:    j.Digits,
+		Period:    time.Duration(j.Period) * time.Second,
+		Counter:   j.Counter,
+	}
+	switch kk.Kind {
+	case KindTOTP, KindHOTP, KindSteam:
+	case "":
+		kk.Kind = KindTOTP
+	default:
+		return fmt.Errorf("otp.Key.UnmarshalJSON: unsupported kind %q", j.Kind)
+	}
+	if kk.Secret, err = decodeBase32(j.Secret); err != nil {
+		return fmt.Errorf("otp.Key.UnmarshalJSON: %w", err)
+	}
+	if j.Algorithm != "" {
+		if kk.Algorithm, err = ParseAlgorithm(j.Algorithm); err != nil {
+			return fmt.Errorf("otp.Key.UnmarshalJSON: unsupported algorithm %q", j.Algorithm)
+		}
+	}
+	switch {
+	case j.Digits == 0 && kk.Kind == KindSteam:
+		kk.Digits = 5
+	case j.Digits == 0:
+		kk.Digits = 6
+	case j.Digits < 1 || j.Digits > 10:
+		return fmt.Errorf("otp.Key.UnmarshalJSON: invalid digits %d: must be between 1 and 10", j.Digits)
+	}
+	switch {
+	case j.Period < 0:
+		return fmt.Errorf("otp.Key.UnmarshalJSON: invalid period %d: must be a positive number of seconds", j.Period)
+	case j.Period == 0 && kk.Kind != KindHOTP:
+		kk.Period = 30 * time.Second
+	}
+	if j.T0 != 0 {
+		kk.T0 = time.Unix(j.T0, 0).UTC()
+	}
+	*k = kk
+	return nil
+}
+
+// Value stores the key as an otpauth:// URL.
+func (k Key) Value() (driver.Value, error) {
+	u, err := k.URL()
+	if err != nil {
+		return nil, err
+	}
+	return u.String(), nil
+}
+
+// Scan reads an otpauth:// URL. A NULL value leaves the key unchanged.
+func (k *Key) Scan(src any) error {
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return k.UnmarshalText([]byte(s))
+	case []byte:
+		return k.UnmarshalText(s)
+	default:
+		return fmt.Errorf("otp.Key.Scan: unsupported type %T", src)
+	}
+}
+
+// LogValue logs the key without the secret.
+//
+// The JSON and text encodings include the secret, so without this a key would
+// be leaked by e.g. slog.JSONHandler.
+func (k Key) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("kind", string(k.Kind)),
+		slog.String("issuer", k.Issuer),
+		slog.String("account", k.Account),
+		slog.Any("secret", k.Secret),
+	)
+}
+
+// URL creates an otpauth:// URL for this key.
+//
+// The algorithm, digits, and period are only added if they're not the default
+// (SHA1, 6 digits, 30 seconds), as not all apps support them. The counter is
+// always added for HOTP. Steam keys use the totp type with encoder=steam.
+//
+// A T0 other than the Unix epoch is added as the non-standard t0 parameter,
+// with a Unix timestamp. Most apps don't support this.
+//
+// If the issuer is empty the label is just the account and the issuer
+// parameter is omitted, e.g. "otpauth://totp/me@example.com?secret=...".
+//
+// Returns an error if the key isn't valid (see Normalize()), or if the period
+// isn't a whole number of seconds or the algorithm isn't SHA1, SHA256, or
+// SHA512, as the URL can't represent them.
+func (k Key) URL() (url, error) {
+	k, err := k.normalize()
+	if err != nil {
+		return url{}, fmt.Errorf("otp.Key.URL: %w", err)
+	}
+	return k.url()
+}
+
+// url creates the URL for a normalized key.
+func (k Key) url() (url, error) {
+	alg, err := FormatAlgorithm(k.Algorithm)
+	if err != nil {
+		return url{}, fmt.Errorf("otp.Key.URL: algorithm %s not supported", k.Algorithm)
+	}
+
+	label := escapeLabel(k.Account)
+	if k.Issuer != "" {
+		label = escapeLabel(k.Issuer) + ":" + label
+	}
+
+	q := neturl.Values{"secret": {base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret)}}
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	if alg != "SHA1" {
+		q.Set("algorithm", alg)
+	}
+	if k.Digits != 6 {
+		q.Set("digits", strconv.Itoa(k.Digits))
+	}
+
+	kind := k.Kind
+	switch kind {
+	case KindHOTP:
+		q.Set("counter", strconv.FormatUint(k.Counter, 10))
+	case KindSteam:
+		kind = KindTOTP
+		q.Set("encoder", "steam")
+		fallthrough
+	default:
+		if k.Period != 30*time.Second {
+			q.Set("period", strconv.FormatInt(int64(k.Period/time.Second), 10))
+		}
+		if !k.T0.IsZero() {
+			q.Set("t0", strconv.FormatInt(k.T0.Unix(), 10))
+		}
+	}
+
+	path, _ := neturl.PathUnescape(label)
+	return url{&neturl.URL{
+		Scheme:  "otpauth",
+		Host:    string(kind),
+		Path:    "/" + path,
+		RawPath: "/" + label,
+		// Spaces as %20 rather than +, as not all apps decode + as a space.
+		RawQuery: strings.ReplaceAll(q.Encode(), "+", "%20"),
+	}}, nil
+}
+
+// escapeLabel escapes the issuer or account for the label; colons need to be
+// escaped, as they're used as the separator.
+func escapeLabel(s string) string {
+	return strings.ReplaceAll(neturl.PathEscape(s), ":", "%3A")
+}
@@ -0,0 +1,493 @@
+package otp_test
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"hash"
+	"reflect"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    otp.Key
+		wantErr string
+	}{
+		{"otpauth://totp/example.net:me@example.net?issuer=example.net&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "example.net", Account: "me@example.net",
+				Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ""},
+		{"OTPAUTH://TOTP/me@example.net?secret=gezdgnbvgy3tqojqgezdgnbvgy3tqojq%3D%3D%3D%3D&algorithm=sha256&digits=8&period=60",
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Account: "me@example.net",
+				Algorithm: crypto.SHA256, Digits: 8, Period: 60 * time.Second}, ""},
+		{"otpauth://hotp/ACME%20Co%3A%20john.doe%40email.com?issuer=ACME%20Co&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&algorithm=SHA512&counter=42",
+			otp.Key{Kind: otp.KindHOTP, Secret: secret, Issuer: "ACME Co", Account: "john.doe@email.com",
+				Algorithm: crypto.SHA512, Digits: 6, Counter: 42}, ""},
+		{"otpauth://hotp/ACME%20Co%3A%20john.doe%40email.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=42",
+			otp.Key{Kind: otp.KindHOTP, Secret: secret, Account: "ACME Co: john.doe@email.com",
+				Algorithm: crypto.SHA1, Digits: 6, Counter: 42}, ""},
+		{"otpauth://hotp/ACME%20Co%3A%20john.doe%40email.com?issuer=Other&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=42",
+			otp.Key{Kind: otp.KindHOTP, Secret: secret, Issuer: "Other", Account: "ACME Co: john.doe@email.com",
+				Algorithm: crypto.SHA1, Digits: 6, Counter: 42}, ""},
+		{"otpauth://totp/Label%3AIssuer:me%3Ame?issuer=Other&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Other", Account: "me:me",
+				Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ""},
+		{"otpauth://totp/Ex%C3%A4mple:%E2%9C%93?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Exämple", Account: "✓",
+				Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ""},
+		{"otpauth://steam/Steam:me?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			otp.Key{Kind: otp.KindSteam, Secret: secret, Issuer: "Steam", Account: "me",
+				Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, ""},
+		{"otpauth://totp/Steam:me?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&encoder=steam",
+			otp.Key{Kind: otp.KindSteam, Secret: secret, Issuer: "Steam", Account: "me",
+				Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, ""},
+
+		{"", otp.Key{}, `otp.ParseURL: scheme is "" and not otpauth`},
+		{"https://totp/x?secret=GEZDGNBV", otp.Key{}, `otp.ParseURL: scheme is "https" and not otpauth`},
+		{"otpauth:///x?secret=GEZDGNBV", otp.Key{}, "otp.ParseURL: type is missing"},
+		{"otpauth://motp/x?secret=GEZDGNBV", otp.Key{}, `otp.ParseURL: unsupported type "motp"`},
+		{"otpauth://totp/x", otp.Key{}, "otp.ParseURL: secret is missing"},
+		{"otpauth://totp/x?secret=GEZ1", otp.Key{}, "otp.ParseURL: otp.ParseSecret: invalid character '1' at position 3 for base32"},
+		{"otpauth://totp/x?secret=====", otp.Key{}, "otp.ParseURL: otp.ParseSecret: secret is empty"},
+		{"otpauth://totp/x?secret=GEZDGNBV&algorithm=MD5", otp.Key{}, `otp.ParseURL: unsupported algorithm "MD5"`},
+		{"otpauth://totp/x?secret=GEZDGNBV&digits=0", otp.Key{}, `otp.ParseURL: invalid digits "0": must be between 1 and 10`},
+		{"otpauth://totp/x?secret=GEZDGNBV&digits=six", otp.Key{}, `otp.ParseURL: invalid digits "six": must be between 1 and 10`},
+		{"otpauth://totp/x?secret=GEZDGNBV&period=-30", otp.Key{}, `otp.ParseURL: invalid period "-30": must be a positive number of seconds`},
+		{"otpauth://hotp/x?secret=GEZDGNBV", otp.Key{}, "otp.ParseURL: counter is required for hotp"},
+		{"otpauth://hotp/x?secret=GEZDGNBV&counter=-1", otp.Key{}, `otp.ParseURL: invalid counter "-1"`},
+		{"otpauth://totp/x?secret=GEZDGNBV&encoder=base26", otp.Key{}, `otp.ParseURL: unsupported encoder "base26"`},
+		{"otpauth://totp/x?secret=GEZDGNBV&x=%zz", otp.Key{}, `otp.ParseURL: invalid query: invalid URL escape "%zz"`},
+		{"otpauth://totp/x%zz?secret=GEZDGNBV", otp.Key{}, `otp.ParseURL: invalid URL escape "%zz"`},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := otp.ParseURL(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseURLRoundTrip(t *testing.T) {
+	for _, s := range [][]byte{secret, secret256, secret512} {
+		k, err := otp.ParseURL(otp.URL(s, "example.com", "me@example.com").String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := otp.Key{Kind: otp.KindTOTP, Secret: s, Issuer: "example.com", Account: "me@example.com",
+			Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}
+		if !reflect.DeepEqual(k, want) {
+			t.Errorf("\nhave: %#v\nwant: %#v", k, want)
+		}
+	}
+
+	tests := []otp.Key{
+		{Kind: otp.KindHOTP, Secret: secret, Account: "x:y", Algorithm: crypto.SHA1, Digits: 6},
+		{Kind: otp.KindHOTP, Secret: secret, Issuer: "x", Account: "y:z", Algorithm: crypto.SHA1, Digits: 6},
+		{Kind: otp.KindTOTP, Secret: secret, Issuer: "a:b", Account: "c:d", Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second},
+	}
+	for _, want := range tests {
+		t.Run("", func(t *testing.T) {
+			u, err := want.URL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			have, err := otp.ParseURL(u.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+			}
+		})
+	}
+}
+
+func TestKeyURL(t *testing.T) {
+	tests := []struct {
+		in   otp.Key
+		want string
+	}{
+		{otp.Key{Secret: secret, Issuer: "example.net", Account: "me@example.net"},
+			"otpauth://totp/example.net:me@example.net?issuer=example.net&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret, Account: "me@example.net", Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second},
+			"otpauth://totp/me@example.net?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret256, Issuer: "x", Account: "y", Algorithm: crypto.SHA256, Digits: 8, Period: time.Minute},
+			"otpauth://totp/x:y?algorithm=SHA256&digits=8&issuer=x&period=60&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"},
+		{otp.Key{Kind: otp.KindHOTP, Secret: secret, Issuer: "x", Account: "y", Algorithm: crypto.SHA512},
+			"otpauth://hotp/x:y?algorithm=SHA512&counter=0&issuer=x&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{otp.Key{Kind: otp.KindHOTP, Secret: secret, Account: "y", Counter: 42, Period: time.Minute},
+			"otpauth://hotp/y?counter=42&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{otp.Key{Kind: otp.KindSteam, Secret: secret, Issuer: "Steam", Account: "me"},
+			"otpauth://totp/Steam:me?digits=5&encoder=steam&issuer=Steam&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{otp.Key{Secret: secret, Issuer: "ACME Co: Inc.", Account: "john doe:1"},
+			"otpauth://totp/ACME%20Co%3A%20Inc.:john%20doe%3A1?issuer=ACME%20Co%3A%20Inc.&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{otp.Key{Secret: secret, Issuer: "Exämple/+&?", Account: "✓"},
+			"otpauth://totp/Ex%C3%A4mple%2F+&%3F:%E2%9C%93?issuer=Ex%C3%A4mple%2F%2B%26%3F&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			u, err := tt.in.URL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			have := u.String()
+			if have != tt.want {
+				t.Errorf("\nhave: %s\nwant: %s", have, tt.want)
+			}
+
+			k, err := otp.ParseURL(have)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if k.Issuer != tt.in.Issuer || k.Account != tt.in.Account {
+				t.Errorf("wrong label after round-trip: %q, %q", k.Issuer, k.Account)
+			}
+			if tt.in.Kind != "" && k.Kind != tt.in.Kind {
+				t.Errorf("wrong kind after round-trip: %q", k.Kind)
+			}
+			if tt.in.Counter != k.Counter {
+				t.Errorf("wrong counter after round-trip: %d", k.Counter)
+			}
+		})
+	}
+}
+
+func TestKeyURLError(t *testing.T) {
+	tests := []struct {
+		in      otp.Key
+		wantErr string
+	}{
+		{otp.Key{Account: "me"}, "otp.Key.URL: secret is empty"},
+		{otp.Key{Kind: "bogus", Secret: secret}, `otp.Key.URL: kind "bogus" not supported`},
+		{otp.Key{Secret: secret, Digits: -3}, "otp.Key.URL: invalid digits -3: must be between 1 and 10"},
+		{otp.Key{Secret: secret, Digits: 11}, "otp.Key.URL: invalid digits 11: must be between 1 and 10"},
+		{otp.Key{Secret: secret, Period: -30 * time.Second}, "otp.Key.URL: invalid period -30s: must be positive"},
+		{otp.Key{Secret: secret, Algorithm: crypto.MD5}, "otp.Key.URL: algorithm MD5 not supported"},
+	}
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := tt.in.URL()
+			if !errorContains(err, tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	k := otp.Key{Secret: secret, Account: "me", Period: 1500 * time.Millisecond}
+	_, err := k.URL()
+	if !errorContains(err, "otp.Key.URL: period 1.5s is not a whole number of seconds") {
+		t.Errorf("wrong error: %v", err)
+	}
+	if _, err := k.MarshalText(); err == nil {
+		t.Error("MarshalText: no error")
+	}
+	if _, err := json.Marshal(k); !errorContains(err, "otp.Key.MarshalJSON: period 1.5s is not a whole number of seconds") {
+		t.Errorf("MarshalJSON: wrong error: %v", err)
+	}
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		in      func() hash.Hash
+		want    crypto.Hash
+		wantErr string
+	}{
+		{sha1.New, crypto.SHA1, ""},
+		{sha256.New, crypto.SHA256, ""},
+		{sha512.New, crypto.SHA512, ""},
+		{sha256.New224, 0, "otp.HashAlgorithm: not SHA1, SHA256, or SHA512"},
+		{md5.New, 0, "otp.HashAlgorithm: not SHA1, SHA256, or SHA512"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := otp.HashAlgorithm(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %s\nwant: %s", have, tt.want)
+			}
+		})
+	}
+
+	// Round-trip from generator parameters to a URL.
+	a, _ := otp.HashAlgorithm(sha256.New)
+	u, err := otp.Key{Secret: secret256, Account: "me", Algorithm: a, Digits: 8}.URL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := otp.ParseURL(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := k.Generator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, _ := g.TokenAt(time.Unix(1111111109, 0)); have != "68084774" {
+		t.Errorf("have %q", have)
+	}
+}
+
+func TestAlgorithm(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    crypto.Hash
+		wantErr string
+	}{
+		{"SHA1", crypto.SHA1, ""},
+		{"sha256", crypto.SHA256, ""},
+		{" SHA-512 ", crypto.SHA512, ""},
+		{"", 0, `otp.ParseAlgorithm: unsupported algorithm ""`},
+		{"MD5", 0, `otp.ParseAlgorithm: unsupported algorithm "MD5"`},
+		{"SHA384", 0, `otp.ParseAlgorithm: unsupported algorithm "SHA384"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			have, err := otp.ParseAlgorithm(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %s\nwant: %s", have, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			s, err := otp.FormatAlgorithm(have)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a, _ := otp.ParseAlgorithm(s); a != have {
+				t.Errorf("round-trip: %s", s)
+			}
+		})
+	}
+
+	if have, _ := otp.FormatAlgorithm(0); have != "SHA1" {
+		t.Errorf("zero value: %q", have)
+	}
+	if _, err := otp.FormatAlgorithm(crypto.MD5); !errorContains(err, "otp.FormatAlgorithm: algorithm MD5 not supported") {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestKeyNormalize(t *testing.T) {
+	tests := []struct {
+		in      otp.Key
+		want    otp.Key
+		wantErr string
+	}{
+		{otp.Key{Secret: secret},
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ""},
+		{otp.Key{Kind: otp.KindSteam, Secret: secret, T0: time.Unix(0, 0)},
+			otp.Key{Kind: otp.KindSteam, Secret: secret, Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, ""},
+		{otp.Key{Kind: otp.KindHOTP, Secret: secret, Algorithm: crypto.SHA256, Digits: 8, Counter: 3},
+			otp.Key{Kind: otp.KindHOTP, Secret: secret, Algorithm: crypto.SHA256, Digits: 8, Counter: 3}, ""},
+		{otp.Key{Secret: secret, Period: time.Minute, T0: time.Unix(60, 0)},
+			otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: crypto.SHA1, Digits: 6, Period: time.Minute, T0: time.Unix(60, 0)}, ""},
+
+		{otp.Key{}, otp.Key{}, "otp.Key.Normalize: secret is empty"},
+		{otp.Key{Kind: "x", Secret: secret}, otp.Key{}, `otp.Key.Normalize: kind "x" not supported`},
+		{otp.Key{Secret: secret, Digits: 11}, otp.Key{}, "otp.Key.Normalize: invalid digits 11: must be between 1 and 10"},
+		{otp.Key{Secret: secret, Period: -time.Second}, otp.Key{}, "otp.Key.Normalize: invalid period -1s: must be positive"},
+		{otp.Key{Secret: secret, Period: time.Millisecond}, otp.Key{}, "otp.Key.Normalize: period 1ms is not a whole number of seconds"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := tt.in.Normalize()
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyGenerator(t *testing.T) {
+	tests := []struct {
+		in   otp.Key
+		at   time.Time
+		want string
+	}{
+		{otp.Key{Secret: secret, Digits: 8}, time.Unix(59, 0), "94287082"},
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret256, Algorithm: crypto.SHA256, Digits: 8}, time.Unix(1111111109, 0), "68084774"},
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret, Digits: 8, T0: time.Unix(3000, 0)}, time.Unix(3059, 0), "94287082"},
+		{otp.Key{Kind: otp.KindTOTP, Secret: secret, Digits: 8, Period: time.Minute}, time.Unix(119, 0), "94287082"},
+		{otp.Key{Kind: otp.KindSteam, Secret: secret}, time.Unix(59, 0), "PV9M4"},
+		{otp.Key{Kind: otp.KindHOTP, Secret: secret, Counter: 9}, time.Time{}, "520489"},
+		{otp.Key{Kind: otp.KindHOTP, Secret: secret[:10], Counter: 0}, time.Time{}, "891490"}, // Short secret
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			g, err := tt.in.Generator()
+			if err != nil {
+				t.Fatal(err)
+			}
+			have := g.Token(0)
+			if !tt.at.IsZero() {
+				have, err = g.TokenAt(tt.at)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+		})
+	}
+
+	_, err := otp.Key{Kind: "motp", Secret: secret}.Generator()
+	if !errorContains(err, `unknown kind "motp"`) {
+		t.Error(err)
+	}
+	_, err = otp.Key{Secret: secret, Algorithm: crypto.MD4}.Generator()
+	if !errorContains(err, "hash algorithm MD4 is not available") {
+		t.Error(err)
+	}
+}
+
+func TestKeyMarshal(t *testing.T) {
+	keys := []otp.Key{
+		{Kind: otp.KindTOTP, Secret: secret, Issuer: "x:y", Account: "me@example.com", Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second},
+		{Kind: otp.KindTOTP, Secret: secret512, Account: "me", Algorithm: crypto.SHA512, Digits: 8, Period: time.Minute, T0: time.Unix(1000, 0).UTC()},
+		{Kind: otp.KindHOTP, Secret: secret, Issuer: "Ex ämple", Account: "me", Algorithm: crypto.SHA256, Digits: 7, Counter: 42},
+		{Kind: otp.KindSteam, Secret: secret, Issuer: "Steam", Account: "me", Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second},
+	}
+
+	for _, k := range keys {
+		t.Run("", func(t *testing.T) {
+			t.Run("text", func(t *testing.T) {
+				text, err := k.MarshalText()
+				if err != nil {
+					t.Fatal(err)
+				}
+				var have otp.Key
+				if err := have.UnmarshalText(text); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(have, k) {
+					t.Errorf("\nhave: %#v\nwant: %#v", have, k)
+				}
+			})
+
+			t.Run("json", func(t *testing.T) {
+				j, err := json.Marshal(k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var have otp.Key
+				if err := json.Unmarshal(j, &have); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(have, k) {
+					t.Errorf("\nhave: %#v\nwant: %#v\njson: %s", have, k, j)
+				}
+			})
+
+			t.Run("sql", func(t *testing.T) {
+				v, err := k.Value()
+				if err != nil {
+					t.Fatal(err)
+				}
+				var have otp.Key
+				if err := have.Scan(v); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(have, k) {
+					t.Errorf("\nhave: %#v\nwant: %#v", have, k)
+				}
+				have = otp.Key{}
+				if err := have.Scan([]byte(v.(string))); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(have, k) {
+					t.Errorf("\nhave: %#v\nwant: %#v", have, k)
+				}
+			})
+		})
+	}
+}
+
+func TestKeyJSON(t *testing.T) {
+	k := otp.Key{Kind: otp.KindHOTP, Secret: secret, Issuer: "x", Account: "y", Algorithm: crypto.SHA256, Digits: 8, Counter: 3}
+	j, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"kind":"hotp","secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","issuer":"x","account":"y","algorithm":"SHA256","digits":8,"counter":3}`
+	if string(j) != want {
+		t.Errorf("\nhave: %s\nwant: %s", j, want)
+	}
+
+	var have otp.Key
+	err = json.Unmarshal([]byte(`"otpauth://hotp/x:y?algorithm=SHA256&counter=3&digits=8&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"`), &have)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, k) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, k)
+	}
+
+	for _, tt := range []struct{ in, wantErr string }{
+		{`{"kind":"motp","secret":"GEZDGNBV"}`, `unsupported kind "motp"`},
+		{`{"secret":""}`, "secret is empty"},
+		{`{"secret":"GEZ1"}`, "otp.ParseSecret: invalid character '1'"},
+		{`{"secret":"GEZDGNBV","algorithm":"MD5"}`, `unsupported algorithm "MD5"`},
+		{`{"secret":"GEZDGNBV","digits":11}`, "invalid digits 11: must be between 1 and 10"},
+		{`{"secret":"GEZDGNBV","digits":-1}`, "invalid digits -1: must be between 1 and 10"},
+		{`{"secret":"GEZDGNBV","period":-30}`, "invalid period -30: must be a positive number of seconds"},
+		{`"https://example.com"`, `scheme is "https"`},
+	} {
+		err := json.Unmarshal([]byte(tt.in), &have)
+		if !errorContains(err, tt.wantErr) {
+			t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+		}
+	}
+
+	if err := have.Scan(42); !errorContains(err, "unsupported type int") {
+		t.Error(err)
+	}
+
+	// Defaults are set like ParseURL().
+	if err := json.Unmarshal([]byte(`{"kind":"steam","secret":"GEZDGNBV"}`), &have); err != nil {
+		t.Fatal(err)
+	}
+	if have.Digits != 5 || have.Period != 30*time.Second || have.Algorithm != crypto.SHA1 {
+		t.Errorf("wrong defaults: %d, %s, %s", have.Digits, have.Period, have.Algorithm)
+	}
+
+	// null is a no-op.
+	have = k
+	for _, f := range []func() error{
+		func() error { return json.Unmarshal([]byte(`null`), &have) },
+		func() error { return have.Scan(nil) },
+	} {
+		if err := f(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(have, k) {
+			t.Errorf("\nhave: %#v\nwant: %#v", have, k)
+		}
+	}
+}
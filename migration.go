@@ -0,0 +1,380 @@
+package otp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"zgo.at/otp/internal/qr"
+)
+
+// Migration is a batch of keys from an otpauth-migration:// URL, as used by the
+// export and import feature of Google Authenticator.
+//
+// Large exports are split in several batches, each of which is shown as a
+// separate QR code.
+type Migration struct {
+	Keys       []Key
+	Version    int
+	BatchSize  int   // Total number of batches in this export.
+	BatchIndex int   // Index of this batch, starting at 0.
+	BatchID    int32 // Identifies the export; the same for all batches.
+}
+
+// Field numbers and enums from the MigrationPayload protobuf message.
+const (
+	migOTPParameters = 1
+	migVersion       = 2
+	migBatchSize     = 3
+	migBatchIndex    = 4
+	migBatchID       = 5
+
+	migSecret    = 1
+	migName      = 2
+	migIssuer    = 3
+	migAlgorithm = 4
+	migDigits    = 5
+	migType      = 6
+	migCounter   = 7
+
+	migHOTP = 1
+	migTOTP = 2
+
+	migSix   = 1
+	migEight = 2
+)
+
+var migAlgorithms = []crypto.Hash{0, crypto.SHA1, crypto.SHA256, crypto.SHA512, crypto.MD5}
+
+// ParseMigrationURL parses an otpauth-migration:// URL, as exported by Google
+// Authenticator.
+//
+// Errors never include the URL, as it contains the secrets.
+func ParseMigrationURL(s string) (Migration, error) {
+	errf := func(f string, a ...any) (Migration, error) {
+		return Migration{}, fmt.Errorf("otp.ParseMigrationURL: "+f, a...)
+	}
+
+	u, err := neturl.Parse(strings.TrimSpace(s))
+	if err != nil {
+		var uErr *neturl.Error
+		if errors.As(err, &uErr) {
+			err = uErr.Err // Don't include the URL.
+		}
+		return errf("%w", err)
+	}
+	if !strings.EqualFold(u.Scheme, "otpauth-migration") {
+		return errf("scheme is %q and not otpauth-migration", u.Scheme)
+	}
+	q, err := neturl.ParseQuery(u.RawQuery)
+	if err != nil {
+		return errf("invalid query: %w", err)
+	}
+	data := q.Get("data")
+	if data == "" {
+		return errf("data is missing")
+	}
+
+	// A + that wasn't escaped is decoded as a space by ParseQuery.
+	data = strings.ReplaceAll(data, " ", "+")
+	payload, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		payload, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+	if err != nil {
+		return errf("invalid data: %w", err)
+	}
+
+	m, err := decodeMigration(payload)
+	if err != nil {
+		return errf("%w", err)
+	}
+	return m, nil
+}
+
+// MigrationURLs creates otpauth-migration:// URLs that can be imported in
+// Google Authenticator.
+//
+// The keys are split in batches of at most perBatch keys, or 10 if perBatch is
+// 0. A batch is split further if it doesn't fit in a QR code of version 25
+// (117×117 modules); larger codes are hard to scan from a screen.
+//
+// Google Authenticator only supports TOTP and HOTP with 6 or 8 digits and a
+// period of 30 seconds; an error is returned for keys it doesn't support.
+func MigrationURLs(keys []Key, perBatch int) ([]url, error) {
+	if perBatch <= 0 {
+		perBatch = 10
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("otp.MigrationURLs: no keys")
+	}
+
+	params := make([][]byte, 0, len(keys))
+	for i, k := range keys {
+		p, err := encodeMigrationKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("otp.MigrationURLs: key %d: %w", i, err)
+		}
+		params = append(params, p)
+	}
+
+	var id [4]byte
+	_, _ = rand.Read(id[:]) // Documented as never returning an error
+	m := Migration{Version: 1, BatchID: int32(binary.BigEndian.Uint32(id[:]) & 0x7fffffff)}
+
+	// Greedily fill the batches. The batch size and index don't affect whether
+	// a batch fits, as they're always encoded as one byte (unless there are
+	// more than 127 batches, in which case nobody will scan them anyway).
+	var batches [][][]byte
+	for i, p := range params {
+		if len(batches) > 0 {
+			last := batches[len(batches)-1]
+			if len(last) < perBatch && fitsQR(m.url(append(last[:len(last):len(last)], p))) {
+				batches[len(batches)-1] = append(last, p)
+				continue
+			}
+		}
+		if !fitsQR(m.url([][]byte{p})) {
+			return nil, fmt.Errorf("otp.MigrationURLs: key %d doesn't fit in a QR code", i)
+		}
+		batches = append(batches, [][]byte{p})
+	}
+
+	urls := make([]url, 0, len(batches))
+	for i, b := range batches {
+		m.BatchSize, m.BatchIndex = len(batches), i
+		urls = append(urls, m.url(b))
+	}
+	return urls, nil
+}
+
+// maxQRVersion is the largest QR code version for migration URLs.
+const maxQRVersion = 25
+
+func fitsQR(u url) bool {
+	return qr.Fits(qr.M, maxQRVersion, u.String())
+}
+
+func (m Migration) url(params [][]byte) url {
+	var b []byte
+	for _, p := range params {
+		b = appendBytes(b, migOTPParameters, p)
+	}
+	b = appendVarint(b, migVersion, uint64(m.Version))
+	b = appendVarint(b, migBatchSize, uint64(m.BatchSize))
+	b = appendVarint(b, migBatchIndex, uint64(m.BatchIndex))
+	b = appendVarint(b, migBatchID, uint64(m.BatchID))
+
+	return url{&neturl.URL{
+		Scheme:   "otpauth-migration",
+		Host:     "offline",
+		RawQuery: "data=" + neturl.QueryEscape(base64.StdEncoding.EncodeToString(b)),
+	}}
+}
+
+func encodeMigrationKey(k Key) ([]byte, error) {
+	var b []byte
+	if len(k.Secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+	b = appendBytes(b, migSecret, k.Secret)
+	b = appendBytes(b, migName, []byte(k.Account))
+	if k.Issuer != "" {
+		b = appendBytes(b, migIssuer, []byte(k.Issuer))
+	}
+
+	alg := crypto.SHA1
+	if k.Algorithm != 0 {
+		alg = k.Algorithm
+	}
+	switch alg {
+	case crypto.SHA1:
+		b = appendVarint(b, migAlgorithm, 1)
+	case crypto.SHA256:
+		b = appendVarint(b, migAlgorithm, 2)
+	case crypto.SHA512:
+		b = appendVarint(b, migAlgorithm, 3)
+	default:
+		return nil, fmt.Errorf("algorithm %s not supported", alg)
+	}
+
+	switch k.Digits {
+	case 0, 6:
+		b = appendVarint(b, migDigits, migSix)
+	case 8:
+		b = appendVarint(b, migDigits, migEight)
+	default:
+		return nil, fmt.Errorf("%d digits not supported", k.Digits)
+	}
+
+	switch k.Kind {
+	case KindTOTP, "":
+		if k.Period != 0 && k.Period != 30*time.Second {
+			return nil, fmt.Errorf("period of %s not supported", k.Period)
+		}
+		if !k.T0.IsZero() && k.T0.Unix() != 0 {
+			return nil, errors.New("T0 not supported")
+		}
+		b = appendVarint(b, migType, migTOTP)
+	case KindHOTP:
+		b = appendVarint(b, migType, migHOTP)
+		b = appendVarint(b, migCounter, k.Counter)
+	default:
+		return nil, fmt.Errorf("kind %q not supported", k.Kind)
+	}
+	return b, nil
+}
+
+func decodeMigration(b []byte) (Migration, error) {
+	var m Migration
+	err := decodeProto(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case migOTPParameters:
+			k, err := decodeMigrationKey(data)
+			if err != nil {
+				return fmt.Errorf("key %d: %w", len(m.Keys), err)
+			}
+			m.Keys = append(m.Keys, k)
+		case migVersion:
+			m.Version = int(int32(v))
+		case migBatchSize:
+			m.BatchSize = int(int32(v))
+		case migBatchIndex:
+			m.BatchIndex = int(int32(v))
+		case migBatchID:
+			m.BatchID = int32(v)
+		}
+		return nil
+	})
+	return m, err
+}
+
+func decodeMigrationKey(b []byte) (Key, error) {
+	k := Key{Kind: KindTOTP, Algorithm: crypto.SHA1, Digits: 6}
+	var name string
+	err := decodeProto(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case migSecret:
+			k.Secret = append([]byte(nil), data...)
+		case migName:
+			name = string(data)
+		case migIssuer:
+			k.Issuer = string(data)
+		case migAlgorithm:
+			if v >= uint64(len(migAlgorithms)) {
+				return fmt.Errorf("unknown algorithm %d", v)
+			}
+			if v > 0 {
+				k.Algorithm = migAlgorithms[v]
+			}
+			if k.Algorithm == crypto.MD5 {
+				return errors.New("algorithm MD5 not supported")
+			}
+		case migDigits:
+			switch v {
+			case 0, migSix:
+				k.Digits = 6
+			case migEight:
+				k.Digits = 8
+			default:
+				return fmt.Errorf("unknown digits %d", v)
+			}
+		case migType:
+			switch v {
+			case 0, migTOTP:
+				k.Kind = KindTOTP
+			case migHOTP:
+				k.Kind = KindHOTP
+			default:
+				return fmt.Errorf("unknown type %d", v)
+			}
+		case migCounter:
+			k.Counter = v
+		}
+		return nil
+	})
+	if err != nil {
+		return Key{}, err
+	}
+	if len(k.Secret) == 0 {
+		return Key{}, errors.New("secret is empty")
+	}
+
+	// The name is often "issuer:account".
+	if i, a, ok := strings.Cut(name, ":"); ok && (k.Issuer == "" || k.Issuer == i) {
+		k.Issuer, name = i, strings.TrimLeft(a, " ")
+	}
+	k.Account = name
+	if k.Kind == KindTOTP {
+		k.Period = 30 * time.Second
+	}
+	return k, nil
+}
+
+// decodeProto calls f for every field in the protobuf message b. For varints
+// v is set, and for length-delimited fields data is set. Other wire types are
+// skipped.
+func decodeProto(b []byte, f func(field int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("invalid protobuf: invalid field tag")
+		}
+		b = b[n:]
+
+		var (
+			field = int(tag >> 3)
+			v     uint64
+			data  []byte
+		)
+		switch tag & 7 {
+		case 0: // varint
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("invalid protobuf: field %d: invalid varint", field)
+			}
+			b = b[n:]
+		case 1: // 64-bit
+			if len(b) < 8 {
+				return fmt.Errorf("invalid protobuf: field %d: truncated", field)
+			}
+			b = b[8:]
+			continue
+		case 2: // length-delimited
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return fmt.Errorf("invalid protobuf: field %d: invalid length", field)
+			}
+			data, b = b[n:n+int(l)], b[n+int(l):]
+		case 5: // 32-bit
+			if len(b) < 4 {
+				return fmt.Errorf("invalid protobuf: field %d: truncated", field)
+			}
+			b = b[4:]
+			continue
+		default:
+			return fmt.Errorf("invalid protobuf: field %d: unsupported wire type %d", field, tag&7)
+		}
+
+		err := f(field, v, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendVarint(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(binary.AppendUvarint(b, uint64(field)<<3), v)
+}
+
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(binary.AppendUvarint(b, uint64(field)<<3|2), uint64(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,162 @@
+package otp_test
+
+import (
+	"crypto"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+)
+
+func TestParseMigrationURL(t *testing.T) {
+	s := "otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGFRlc3QxOnRlc3QxQGV4YW1wbGUxLmNvbRoFVGVzdDEgASgBMAIKMQoKSGVsbG8h3q2%2B7xIYVGVzdDI6dGVzdDJAZXhhbXBsZTIuY29tGgVUZXN0MiABKAEwAgoxCgpIZWxsbyHerb7vEhhUZXN0Mzp0ZXN0M0BleGFtcGxlMy5jb20aBVRlc3QzIAEoATACEAEYASAAKI3orYEE"
+	have, err := otp.ParseMigrationURL(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec := []byte("Hello!\xde\xad\xbe\xef")
+	want := otp.Migration{
+		Version: 1, BatchSize: 1, BatchIndex: 0, BatchID: 1076589581,
+		Keys: []otp.Key{
+			{Kind: otp.KindTOTP, Secret: sec, Issuer: "Test1", Account: "test1@example1.com", Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second},
+			{Kind: otp.KindTOTP, Secret: sec, Issuer: "Test2", Account: "test2@example2.com", Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second},
+			{Kind: otp.KindTOTP, Secret: sec, Issuer: "Test3", Account: "test3@example3.com", Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second},
+		},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+	}
+
+	// Unescaped + in the data.
+	if _, err := otp.ParseMigrationURL(strings.ReplaceAll(s, "%2B", "+")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseMigrationURLError(t *testing.T) {
+	tests := []struct {
+		in, wantErr string
+	}{
+		{"otpauth://totp/x?secret=GEZDGNBV", `scheme is "otpauth" and not otpauth-migration`},
+		{"otpauth-migration://offline", "data is missing"},
+		{"otpauth-migration://offline?data=!!!", "invalid data"},
+		{"otpauth-migration://offline?data=CgA=", "key 0: secret is empty"},
+		{"otpauth-migration://offline?data=CgQKAQEg", "key 0: invalid protobuf: field 4: invalid varint"},
+		{"otpauth-migration://offline?data=CgUKAQEgBA==", "key 0: algorithm MD5 not supported"},
+		{"otpauth-migration://offline?data=CgUKAQEoAw==", "key 0: unknown digits 3"},
+		{"otpauth-migration://offline?data=Cg==", "invalid protobuf: field 1: invalid length"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := otp.ParseMigrationURL(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrationURLs(t *testing.T) {
+	var keys []otp.Key
+	for i := range 25 {
+		k := otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: fmt.Sprintf("user%d@example.com", i),
+			Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}
+		if i%5 == 0 {
+			k = otp.Key{Kind: otp.KindHOTP, Secret: secret512, Account: fmt.Sprintf("hotp%d", i),
+				Algorithm: crypto.SHA512, Digits: 8, Counter: uint64(i)}
+		}
+		keys = append(keys, k)
+	}
+
+	urls, err := otp.MigrationURLs(keys, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 3 {
+		t.Fatalf("%d batches", len(urls))
+	}
+
+	var (
+		have []otp.Key
+		id   int32
+	)
+	for i, u := range urls {
+		if _, err := u.PNGDataURL(200); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := otp.ParseMigrationURL(u.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.BatchSize != 3 || m.BatchIndex != i || m.Version != 1 {
+			t.Errorf("wrong batch: %d/%d (version %d)", m.BatchIndex, m.BatchSize, m.Version)
+		}
+		if i == 0 {
+			id = m.BatchID
+		} else if m.BatchID != id {
+			t.Errorf("batch ID differs: %d != %d", m.BatchID, id)
+		}
+		have = append(have, m.Keys...)
+	}
+	if !reflect.DeepEqual(have, keys) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, keys)
+	}
+
+	t.Run("split to fit QR", func(t *testing.T) {
+		big := make([]otp.Key, 20)
+		for i := range big {
+			big[i] = otp.Key{Secret: secret512, Issuer: strings.Repeat("x", 100), Account: strings.Repeat("y", 100)}
+		}
+		urls, err := otp.MigrationURLs(big, 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(urls) < 2 {
+			t.Fatalf("%d batches", len(urls))
+		}
+		var n int
+		for _, u := range urls {
+			if l := len(u.String()); l > 997 { // Capacity of version 25, level M.
+				t.Errorf("URL is %d bytes", l)
+			}
+			m, err := otp.ParseMigrationURL(u.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			n += len(m.Keys)
+		}
+		if n != 20 {
+			t.Errorf("%d keys", n)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			k       otp.Key
+			wantErr string
+		}{
+			{otp.Key{}, "key 0: secret is empty"},
+			{otp.Key{Secret: secret, Digits: 7}, "key 0: 7 digits not supported"},
+			{otp.Key{Secret: secret, Period: time.Minute}, "key 0: period of 1m0s not supported"},
+			{otp.Key{Secret: secret, Algorithm: crypto.MD5}, "key 0: algorithm MD5 not supported"},
+			{otp.Key{Secret: secret, Kind: otp.KindSteam}, `key 0: kind "steam" not supported`},
+			{otp.Key{Secret: secret, T0: time.Unix(10, 0)}, "key 0: T0 not supported"},
+			{otp.Key{Secret: secret, Account: strings.Repeat("x", 3000)}, "key 0 doesn't fit in a QR code"},
+		}
+		for _, tt := range tests {
+			_, err := otp.MigrationURLs([]otp.Key{tt.k}, 0)
+			if !errorContains(err, tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		}
+		if _, err := otp.MigrationURLs(nil, 0); !errorContains(err, "no keys") {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package otp_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+)
+
+func TestOCRA(t *testing.T) {
+	// From RFC 6287 Appendix C
+	var (
+		key20, _ = hex.DecodeString("3132333435363738393031323334353637383930")
+		key32, _ = hex.DecodeString("3132333435363738393031323334353637383930313233343536373839303132")
+		key64, _ = hex.DecodeString("31323334353637383930313233343536373839303132333435363738393031323334353637383930313233343536373839303132333435363738393031323334")
+		ts       = time.Unix(0x132d0b6*60, 0)
+	)
+
+	tests := []struct {
+		suite string
+		key   []byte
+		in    otp.OCRAInput
+		want  string
+	}{
+		// C.1 One-way challenge-response
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "00000000"}, "237653"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "11111111"}, "243178"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "22222222"}, "653583"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "33333333"}, "740991"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "44444444"}, "608993"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "55555555"}, "388898"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "66666666"}, "816933"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "77777777"}, "224598"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "88888888"}, "750600"},
+		{"OCRA-1:HOTP-SHA1-6:QN08", key20, otp.OCRAInput{Question: "99999999"}, "294470"},
+
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 0, Question: "12345678", PIN: "1234"}, "65347737"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 1, Question: "12345678", PIN: "1234"}, "86775851"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 2, Question: "12345678", PIN: "1234"}, "78192410"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 3, Question: "12345678", PIN: "1234"}, "71565254"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 4, Question: "12345678", PIN: "1234"}, "10104329"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 5, Question: "12345678", PIN: "1234"}, "65983500"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 6, Question: "12345678", PIN: "1234"}, "70069104"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 7, Question: "12345678", PIN: "1234"}, "91771096"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 8, Question: "12345678", PIN: "1234"}, "75011558"},
+		{"OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", key32, otp.OCRAInput{Counter: 9, Question: "12345678", PIN: "1234"}, "08522129"},
+
+		{"OCRA-1:HOTP-SHA256-8:QN08-PSHA1", key32, otp.OCRAInput{Question: "00000000", PIN: "1234"}, "83238735"},
+		{"OCRA-1:HOTP-SHA256-8:QN08-PSHA1", key32, otp.OCRAInput{Question: "11111111", PIN: "1234"}, "01501458"},
+		{"OCRA-1:HOTP-SHA256-8:QN08-PSHA1", key32, otp.OCRAInput{Question: "22222222", PIN: "1234"}, "17957585"},
+		{"OCRA-1:HOTP-SHA256-8:QN08-PSHA1", key32, otp.OCRAInput{Question: "33333333", PIN: "1234"}, "86776967"},
+		{"OCRA-1:HOTP-SHA256-8:QN08-PSHA1", key32, otp.OCRAInput{Question: "44444444", PIN: "1234"}, "86807031"},
+
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 0, Question: "00000000"}, "07016083"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 1, Question: "11111111"}, "63947962"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 2, Question: "22222222"}, "70123924"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 3, Question: "33333333"}, "25341727"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 4, Question: "44444444"}, "33203315"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 5, Question: "55555555"}, "34205738"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 6, Question: "66666666"}, "44343969"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 7, Question: "77777777"}, "51946085"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 8, Question: "88888888"}, "20403879"},
+		{"OCRA-1:HOTP-SHA512-8:C-QN08", key64, otp.OCRAInput{Counter: 9, Question: "99999999"}, "31409299"},
+
+		{"OCRA-1:HOTP-SHA512-8:QN08-T1M", key64, otp.OCRAInput{Question: "00000000", Time: ts}, "95209754"},
+		{"OCRA-1:HOTP-SHA512-8:QN08-T1M", key64, otp.OCRAInput{Question: "11111111", Time: ts}, "55907591"},
+		{"OCRA-1:HOTP-SHA512-8:QN08-T1M", key64, otp.OCRAInput{Question: "22222222", Time: ts}, "22048402"},
+		{"OCRA-1:HOTP-SHA512-8:QN08-T1M", key64, otp.OCRAInput{Question: "33333333", Time: ts}, "24218844"},
+		{"OCRA-1:HOTP-SHA512-8:QN08-T1M", key64, otp.OCRAInput{Question: "44444444", Time: ts}, "36209546"},
+
+		// C.2 Mutual challenge-response
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "CLI22220SRV11110"}, "28247970"},
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "CLI22221SRV11111"}, "01984843"},
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "SRV11110CLI22220"}, "15510767"},
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "SRV11111CLI22221"}, "90175646"},
+
+		// C.3 Plain signature
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "SIG10000"}, "53095496"},
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "SIG11000"}, "04110475"},
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "SIG12000"}, "31331128"},
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "SIG13000"}, "76028668"},
+		{"OCRA-1:HOTP-SHA256-8:QA08", key32, otp.OCRAInput{Question: "SIG14000"}, "46554205"},
+
+		{"OCRA-1:HOTP-SHA512-8:QA10-T1M", key64, otp.OCRAInput{Question: "SIG1000000", Time: ts}, "77537423"},
+		{"OCRA-1:HOTP-SHA512-8:QA10-T1M", key64, otp.OCRAInput{Question: "SIG1100000", Time: ts}, "31970405"},
+		{"OCRA-1:HOTP-SHA512-8:QA10-T1M", key64, otp.OCRAInput{Question: "SIG1200000", Time: ts}, "10235557"},
+		{"OCRA-1:HOTP-SHA512-8:QA10-T1M", key64, otp.OCRAInput{Question: "SIG1300000", Time: ts}, "95213541"},
+		{"OCRA-1:HOTP-SHA512-8:QA10-T1M", key64, otp.OCRAInput{Question: "SIG1400000", Time: ts}, "65360607"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.suite, func(t *testing.T) {
+			s, err := otp.ParseOCRASuite(tt.suite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			have, err := s.Token(tt.key, tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+
+			ok, err := s.Verify(tt.key, tt.want, tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Error("Verify() failed")
+			}
+			q := []byte(tt.in.Question)
+			q[0] ^= 1
+			tt.in.Question = string(q)
+			if ok, _ := s.Verify(tt.key, tt.want, tt.in); ok {
+				t.Error("Verify() succeeded with different question")
+			}
+		})
+	}
+}
+
+func TestParseOCRASuite(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr string
+	}{
+		{"OCRA-1:HOTP-SHA1-6:QN08", ""},
+		{"OCRA-1:HOTP-SHA512-0:C-QH64-PSHA256-S512-T48H", ""},
+		{"OCRA-1:HOTP-SHA1-10:QA04-S064-T30S", ""},
+
+		{"", "must have three parts"},
+		{"OCRA-2:HOTP-SHA1-6:QN08", `unsupported version "OCRA-2"`},
+		{"OCRA-1:TOTP-SHA1-6:QN08", `invalid crypto function "TOTP-SHA1-6"`},
+		{"OCRA-1:HOTP-MD5-6:QN08", `unsupported hash "MD5"`},
+		{"OCRA-1:HOTP-SHA1-3:QN08", `invalid number of digits "3"`},
+		{"OCRA-1:HOTP-SHA1-11:QN08", `invalid number of digits "11"`},
+		{"OCRA-1:HOTP-SHA1-6:C", "question (Q) is required"},
+		{"OCRA-1:HOTP-SHA1-6:QX08", `invalid question format "QX08"`},
+		{"OCRA-1:HOTP-SHA1-6:QN65", `invalid question length "QN65"`},
+		{"OCRA-1:HOTP-SHA1-6:QN08-PMD5", `unsupported PIN hash "PMD5"`},
+		{"OCRA-1:HOTP-SHA1-6:QN08-S64", `invalid session information "S64"`},
+		{"OCRA-1:HOTP-SHA1-6:QN08-T60M", `invalid timestamp "T60M"`},
+		{"OCRA-1:HOTP-SHA1-6:QN08-T1D", `invalid timestamp "T1D"`},
+		{"OCRA-1:HOTP-SHA1-6:QN08-C", `invalid data input "C"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			s, err := otp.ParseOCRASuite(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if err == nil && s.String() != tt.in {
+				t.Errorf("String(): %q", s.String())
+			}
+		})
+	}
+}
@@ -1,15 +1,21 @@
-// Package otp implemnts HOTP and TOTP one-time passwords.
+// Package otp implements HOTP, TOTP, and OCRA one-time passwords.
 package otp
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/hmac"
 	"crypto/rand"
-	"encoding/base32"
+	"crypto/sha1"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"hash"
 	"image/png"
+	"io"
+	"log/slog"
 	"math"
 	neturl "net/url"
 	"strconv"
@@ -22,7 +28,7 @@ import (
 // Secret generates a new shared secret.
 //
 // It's not required to use this function specifically. It's just here for
-// convenience.
+// convenience. Use NewSecret() to set the length or source of randomness.
 func Secret() []byte {
 	// rfc4226 says length MUST be at least 16 bytes, and RECOMMENDs 20 bytes. I
 	// don't see any service using more than 20, so should be fine to just
@@ -37,11 +43,50 @@ type (
 	url struct {
 		url *neturl.URL
 	}
-	generator struct {
+
+	// Generator generates and verifies HMAC one-time passwords.
+	//
+	// A Generator must be created with New(), NewGenerator(), NewTOTP(),
+	// NewSteam(), or Key.Generator(); the zero value can't be used.
+	Generator struct {
 		length  int
 		counter CounterFunc
 		h       func() hash.Hash
-		secret  []byte
+		secret  SecretKey
+		totp    *TOTPConfig
+		enc     TokenEncoder
+	}
+
+	// GeneratorOptions are the options for NewGenerator().
+	GeneratorOptions struct {
+		// Shared secret; must be at least 16 bytes (128 bits), which is the
+		// minimum from RFC4226. The generator keeps a copy.
+		Secret []byte
+
+		// Accept secrets shorter than 16 bytes. This can be useful when
+		// importing existing secrets, as some services use 10-byte (80 bit)
+		// secrets.
+		AllowShortSecret bool
+
+		// Token length, from 1 to 10. Default is 6.
+		Length int
+
+		// Hash function to use. Default is SHA-1.
+		Hash func() hash.Hash
+
+		// Encoder for the tokens. Default is Decimal().
+		Encoder TokenEncoder
+
+		// Counter function; the default is TOTP with Period.
+		Counter CounterFunc
+
+		// TOTP period (time step); must be at least one second. Default is 30
+		// seconds. This can't be used together with Counter.
+		Period time.Duration
+
+		// TOTP start time; default is the Unix epoch. This can't be used
+		// together with Counter.
+		T0 time.Time
 	}
 
 	// CounterFunc is a function that is called when generating a one-time password
@@ -59,45 +104,127 @@ type (
 //
 // Offset indicates that we want the token relative to the current token by
 // offset (eg. -1 for the previous token).
-func (g generator) Token(offset int) string {
+//
+// Panics if the Generator wasn't created with New() or one of the other
+// constructors.
+func (g Generator) Token(offset int) string {
+	if g.h == nil {
+		panic("otp.Generator.Token: Generator must be created with New() or NewGenerator()")
+	}
 	hm := hmac.New(g.h, g.secret)
 
 	// Never returns an error unless the write fails (which is a hash writer) or
 	// invalid input.
 	_ = binary.Write(hm, binary.BigEndian, g.counter(offset))
 
-	var (
-		h   = hm.Sum(nil)
-		off = h[len(h)-1] & 0xf
-		v   = ((int(h[off]))&0x7f)<<24 |
-			((int(h[off+1] & 0xff)) << 16) |
-			((int(h[off+2] & 0xff)) << 8) |
-			(int(h[off+3]) & 0xff)
-		s = strconv.Itoa(v % int(math.Pow10(g.length)))
-	)
-	if ll := g.length - len(s); ll > 0 {
+	return g.encoder().Encode(uint32(truncate(hm.Sum(nil))), g.length)
+}
+
+func (g Generator) encoder() TokenEncoder {
+	if g.enc == nil {
+		return Decimal()
+	}
+	return g.enc
+}
+
+// truncate a HMAC value to a 31-bit integer, as described in RFC4226 section
+// 5.3.
+func truncate(h []byte) int {
+	off := h[len(h)-1] & 0xf
+	return ((int(h[off]))&0x7f)<<24 |
+		((int(h[off+1] & 0xff)) << 16) |
+		((int(h[off+2] & 0xff)) << 8) |
+		(int(h[off+3]) & 0xff)
+}
+
+// decimal formats v as a zero-padded decimal token of length digits.
+func decimal(v, length int) string {
+	s := strconv.Itoa(v % int(math.Pow10(length)))
+	if ll := length - len(s); ll > 0 {
 		s = strings.Repeat("0", ll) + s
 	}
 	return s
 }
 
+// Errors returned by VerifyDetailed() and friends.
+var (
+	ErrInvalidFormat = errors.New("otp: token has invalid format")
+	ErrNoMatch       = errors.New("otp: token does not match")
+	ErrReplayed      = errors.New("otp: token was already used")
+	ErrNotTOTP       = errors.New("otp: not a TOTP generator")
+)
+
+// VerifyResult is the result of a successful verification.
+type VerifyResult struct {
+	// Offset that matched, relative to the current token. For TOTP this is the
+	// clock drift of the client in steps: -1 means the client is one step
+	// behind.
+	Offset int
+
+	// Counter is the absolute counter value that matched: the time step for
+	// TOTP, or the counter for HOTP.
+	Counter uint64
+}
+
 // Verify a token.
 //
 // If offset is higher than 0, it will also accept tokens from -offset to
 // +offset. This can be useful to allow some clock skew for e.g. TOTP.
-func (g generator) Verify(token string, offset int) bool {
-	for i := -offset; i <= offset; i++ {
-		if token == g.Token(i) {
-			return true
-		}
+//
+// All tokens in the window are always generated and compared in constant time,
+// so the time this takes doesn't depend on the token.
+func (g Generator) Verify(token string, offset int) bool {
+	_, err := g.VerifyDetailed(token, offset)
+	return err == nil
+}
+
+// VerifyDetailed verifies a token, like Verify(), but returns which counter
+// matched.
+//
+// Returns ErrInvalidFormat if the token can never be valid (e.g. wrong length)
+// or ErrNoMatch if it doesn't match any token in the window.
+func (g Generator) VerifyDetailed(token string, offset int) (VerifyResult, error) {
+	token, ok := g.encoder().Normalize(token, g.length)
+	if !ok {
+		return VerifyResult{}, ErrInvalidFormat
+	}
+
+	// Read the counter only once, so that the window and the reported counter
+	// don't shift if the clock crosses a step boundary.
+	c := g.counter(0)
+	g.counter = HOTP(c)
+	i, ok := g.match(token, -offset, offset)
+	if !ok {
+		return VerifyResult{}, ErrNoMatch
 	}
-	return false
+	return VerifyResult{Offset: i, Counter: c + uint64(i)}, nil
+}
+
+// match returns the first offset from..to for which the token matches.
+//
+// It always generates every token in the window and compares them in constant
+// time, so that the time taken doesn't reveal anything about the token or which
+// offset matched.
+func (g Generator) match(token string, from, to int) (int, bool) {
+	var (
+		t          = []byte(token)
+		found, off int
+	)
+	for i := from; i <= to; i++ {
+		eq := subtle.ConstantTimeCompare(t, []byte(g.Token(i)))
+		off = subtle.ConstantTimeSelect(eq&^found, i, off)
+		found |= eq
+	}
+	return off, found == 1
 }
 
 // New returns a generator to generate and verify HMAC one-time passwords.
 //
-// Panics if tokenLength is <= 0 or if any of the other parameters are nil.
-func New(sharedSecret []byte, tokenLength int, hash func() hash.Hash, c CounterFunc) generator {
+// The generator keeps a copy of sharedSecret.
+//
+// Panics if tokenLength is <= 0 or if any of the other parameters are nil. Use
+// NewGenerator() to validate user-supplied parameters.
+func New(sharedSecret []byte, tokenLength int, hash func() hash.Hash, c CounterFunc) Generator {
 	if tokenLength <= 0 {
 		panic("otp.New: tokenLength must be greater than 0")
 	}
@@ -110,28 +237,88 @@ func New(sharedSecret []byte, tokenLength int, hash func() hash.Hash, c CounterF
 	if len(sharedSecret) == 0 {
 		panic("otp.New: sharedSecret must not be empty")
 	}
-	return generator{tokenLength, c, hash, sharedSecret}
+	return Generator{length: tokenLength, counter: c, h: hash, secret: bytes.Clone(sharedSecret)}
+}
+
+// NewGenerator returns a generator to generate and verify HMAC one-time
+// passwords, returning an error if any of the options are invalid.
+func NewGenerator(opt GeneratorOptions) (*Generator, error) {
+	if opt.Length == 0 {
+		opt.Length = 6
+	}
+	if opt.Hash == nil {
+		opt.Hash = sha1.New
+	}
+
+	if opt.Length < 1 || opt.Length > 10 {
+		return nil, fmt.Errorf("otp.NewGenerator: Length must be between 1 and 10, not %d", opt.Length)
+	}
+	if len(opt.Secret) == 0 {
+		return nil, errors.New("otp.NewGenerator: Secret must not be empty")
+	}
+	if len(opt.Secret) < 16 && !opt.AllowShortSecret {
+		return nil, fmt.Errorf("otp.NewGenerator: Secret must be at least 16 bytes, not %d", len(opt.Secret))
+	}
+	if s := opt.Hash().Size(); s < 20 {
+		return nil, fmt.Errorf("otp.NewGenerator: Hash must produce at least 20 bytes, not %d", s)
+	}
+	if opt.Counter != nil && (opt.Period != 0 || !opt.T0.IsZero()) {
+		return nil, errors.New("otp.NewGenerator: can't use both Period or T0 and Counter")
+	}
+
+	g := &Generator{length: opt.Length, counter: opt.Counter, h: opt.Hash, secret: bytes.Clone(opt.Secret), enc: opt.Encoder}
+	if opt.Counter == nil {
+		if opt.Period == 0 {
+			opt.Period = 30 * time.Second
+		}
+		if opt.Period < time.Second {
+			return nil, fmt.Errorf("otp.NewGenerator: Period must be at least 1s, not %s", opt.Period)
+		}
+		g.totp = &TOTPConfig{T0: opt.T0, Period: opt.Period}
+		g.counter = g.totp.Counter()
+	}
+	return g, nil
+}
+
+// LogValue logs the parameters of the generator; the secret is always
+// redacted.
+func (g Generator) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.Int("length", g.length)}
+	if g.totp != nil {
+		attrs = append(attrs, slog.Duration("period", g.totp.Period))
+		if !g.totp.T0.IsZero() {
+			attrs = append(attrs, slog.Time("t0", g.totp.T0))
+		}
+	}
+	return slog.GroupValue(append(attrs, slog.Any("secret", g.secret))...)
+}
+
+// Format the generator's parameters for all verbs, with the secret redacted.
+func (g Generator) Format(f fmt.State, verb rune) {
+	io.WriteString(f, "otp.Generator{")
+	for i, a := range g.LogValue().Group() {
+		if i > 0 {
+			io.WriteString(f, " ")
+		}
+		io.WriteString(f, a.String())
+	}
+	io.WriteString(f, "}")
 }
 
 // TOTP returns a counter function to generate TOTP tokens as defined in
 // RFC6238.
 //
-// TOTP tokens are time-based and valid for step duration. It will use 30
+// TOTP tokens are time-based and valid for period duration. It will use 30
 // seconds if zero, which is a reasonable default, but in some cases where clock
 // skew is expected a longer value may be used.
 //
 // Providing the time can be useful to provide a fixed time for testing. It uses
 // time.Now() if nil.
-func TOTP(step time.Duration, t func() time.Time) CounterFunc {
-	if step == 0 {
-		step = 30 * time.Second
-	}
-	if t == nil {
-		t = time.Now
-	}
-	return func(offset int) uint64 {
-		return uint64(math.Floor(float64(t().Add(time.Duration(offset)*step).Unix()) / step.Seconds()))
-	}
+//
+// Use NewTOTP() to create a generator that supports TokenAt() and VerifyAt(),
+// or to set a different T0.
+func TOTP(period time.Duration, t func() time.Time) CounterFunc {
+	return TOTPConfig{Period: period, Now: t}.Counter()
 }
 
 // String returns the URL.
@@ -160,14 +347,15 @@ func (u url) PNGDataURL(size int) (string, error) {
 
 // URL creates an URL that can be used by most OTP apps, such as FreeOTP, Yubico
 // Authenticator, Google Authenticator, etc.
+//
+// This is for TOTP with the default parameters (SHA1, 6 digits, 30 second
+// step). Use Key.URL() for other parameters.
+//
+// If issuer is empty the label is just the email and the issuer parameter is
+// omitted. Older versions used an empty issuer in both places, i.e.
+// "otpauth://totp/:me@example.com?issuer=&secret=...".
 func URL(key []byte, issuer, email string) url {
-	return url{&neturl.URL{
-		Scheme: "otpauth",
-		Host:   "totp",
-		Path:   issuer + ":" + email,
-		RawQuery: neturl.Values{
-			"issuer": {issuer},
-			"secret": {base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)},
-		}.Encode(),
-	}}
+	u, _ := Key{Kind: KindTOTP, Secret: key, Issuer: issuer, Account: email,
+		Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}.url()
+	return u // Can't fail with the default parameters.
 }
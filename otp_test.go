@@ -2,11 +2,16 @@ package otp_test
 
 import (
 	"bytes"
+	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"errors"
+	"fmt"
 	"hash"
+	"log/slog"
 	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -45,6 +50,16 @@ func wantPanic(t *testing.T, want string) {
 	}
 }
 
+func errorContains(have error, want string) bool {
+	if have == nil {
+		return want == ""
+	}
+	if want == "" {
+		return false
+	}
+	return strings.Contains(have.Error(), want)
+}
+
 func TestURL(t *testing.T) {
 	tests := []struct {
 		secret []byte
@@ -76,6 +91,12 @@ func TestURL(t *testing.T) {
 			email:  "me@example.com",
 			want:   "otpauth://totp/example.com:me@example.com?issuer=example.com&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA",
 		},
+		{
+			secret: secret,
+			issuer: "",
+			email:  "me@example.net",
+			want:   "otpauth://totp/me@example.net?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,6 +222,87 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+func TestVerifyDetailed(t *testing.T) {
+	o := otp.New(secret, 8, sha1.New, otp.TOTP(0, func() time.Time {
+		return time.Date(2005, 3, 18, 1, 58, 29, 0, time.UTC)
+	}))
+
+	tests := []struct {
+		token   string
+		window  int
+		want    otp.VerifyResult
+		wantErr error
+	}{
+		{"07081804", 0, otp.VerifyResult{Offset: 0, Counter: 0x23523ec}, nil},
+		{"07081804", 2, otp.VerifyResult{Offset: 0, Counter: 0x23523ec}, nil},
+		{"14050471", 1, otp.VerifyResult{Offset: 1, Counter: 0x23523ed}, nil},
+		{o.Token(-2), 2, otp.VerifyResult{Offset: -2, Counter: 0x23523ea}, nil},
+
+		{"14050471", 0, otp.VerifyResult{}, otp.ErrNoMatch},
+		{"00000000", 5, otp.VerifyResult{}, otp.ErrNoMatch},
+		{"0708180", 1, otp.VerifyResult{}, otp.ErrInvalidFormat},
+		{"070818044", 1, otp.VerifyResult{}, otp.ErrInvalidFormat},
+		{"0708180x", 1, otp.VerifyResult{}, otp.ErrInvalidFormat},
+		{"", 1, otp.VerifyResult{}, otp.ErrInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := o.VerifyDetailed(tt.token, tt.window)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %+v\nwant: %+v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDetailedStepBoundary(t *testing.T) {
+	// Every call advances the clock by a millisecond, starting just before the
+	// step boundary.
+	now := time.Unix(29, 998e6)
+	o := otp.New(secret, 6, sha1.New, otp.TOTP(0, func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}))
+	tok := otp.New(secret, 6, sha1.New, otp.HOTP(0)).Token(0)
+
+	have, err := o.VerifyDetailed(tok, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (otp.VerifyResult{Offset: 0, Counter: 0}); have != want {
+		t.Errorf("\nhave: %+v\nwant: %+v", have, want)
+	}
+}
+
+func TestVerifyConstantTime(t *testing.T) {
+	var n int
+	h := func() hash.Hash { n++; return sha256.New() }
+	o := otp.New(secret, 8, h, otp.TOTP(0, func() time.Time {
+		return time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	}))
+
+	count := func(token string) int {
+		t.Helper()
+		n = 0
+		o.Verify(token, 2)
+		return n
+	}
+
+	want := count("00000000")
+	if want == 0 {
+		t.Fatal("no HMAC computations")
+	}
+	for _, tok := range []string{o.Token(-2), o.Token(0), o.Token(2), o.Token(3), "99999999"} {
+		if have := count(tok); have != want {
+			t.Errorf("%s: %d HMAC computations; want %d", tok, have, want)
+		}
+	}
+}
+
 func TestPanic(t *testing.T) {
 	tests := []struct {
 		want string
@@ -212,6 +314,7 @@ func TestPanic(t *testing.T) {
 		{"otp.New: hash func must not be nil", func() { otp.New(secret, 8, nil, otp.TOTP(0, nil)) }},
 		{"otp.New: sharedSecret must not be empty", func() { otp.New(nil, 8, sha1.New, otp.TOTP(0, nil)) }},
 		{"otp.New: sharedSecret must not be empty", func() { otp.New([]byte{}, 8, sha1.New, otp.TOTP(0, nil)) }},
+		{"otp.Generator.Token: Generator must be created with New() or NewGenerator()", func() { otp.Generator{}.Token(0) }},
 	}
 
 	for _, tt := range tests {
@@ -222,6 +325,82 @@ func TestPanic(t *testing.T) {
 	}
 }
 
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		opt     otp.GeneratorOptions
+		wantErr string
+	}{
+		{otp.GeneratorOptions{Secret: secret}, ""},
+		{otp.GeneratorOptions{Secret: secret512, Length: 10, Hash: sha512.New, Period: time.Minute}, ""},
+		{otp.GeneratorOptions{Secret: secret, Length: 1, Counter: otp.HOTP(0)}, ""},
+		{otp.GeneratorOptions{Secret: secret[:10], AllowShortSecret: true}, ""},
+
+		{otp.GeneratorOptions{}, "otp.NewGenerator: Secret must not be empty"},
+		{otp.GeneratorOptions{Secret: secret[:15]}, "otp.NewGenerator: Secret must be at least 16 bytes, not 15"},
+		{otp.GeneratorOptions{Secret: secret, Length: -1}, "otp.NewGenerator: Length must be between 1 and 10, not -1"},
+		{otp.GeneratorOptions{Secret: secret, Length: 11}, "otp.NewGenerator: Length must be between 1 and 10, not 11"},
+		{otp.GeneratorOptions{Secret: secret, Hash: md5.New}, "otp.NewGenerator: Hash must produce at least 20 bytes, not 16"},
+		{otp.GeneratorOptions{Secret: secret, Period: -time.Second}, "otp.NewGenerator: Period must be at least 1s, not -1s"},
+		{otp.GeneratorOptions{Secret: secret, Period: time.Millisecond}, "otp.NewGenerator: Period must be at least 1s, not 1ms"},
+		{otp.GeneratorOptions{Secret: secret, Period: time.Minute, Counter: otp.HOTP(0)}, "otp.NewGenerator: can't use both Period or T0 and Counter"},
+		{otp.GeneratorOptions{Secret: secret, T0: time.Unix(1, 0), Counter: otp.HOTP(0)}, "otp.NewGenerator: can't use both Period or T0 and Counter"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			g, err := otp.NewGenerator(tt.opt)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+			if !g.Verify(g.Token(0), 0) {
+				t.Error("Verify() failed")
+			}
+		})
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		g, err := otp.NewGenerator(otp.GeneratorOptions{Secret: secret, Counter: otp.HOTP(0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := g.Token(0); have != "755224" {
+			t.Errorf("have %q", have)
+		}
+	})
+}
+
+func TestGeneratorLog(t *testing.T) {
+	g, err := otp.NewGenerator(otp.GeneratorOptions{Secret: secret, Period: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "otp.Generator{length=6 period=1m0s secret=[redacted]}"
+	for _, f := range []string{"%v", "%+v", "%#v", "%s", "%x", "%d"} {
+		if have := fmt.Sprintf(f, g); have != want {
+			t.Errorf("%s\nhave: %s\nwant: %s", f, have, want)
+		}
+		if have := fmt.Sprintf(f, *g); have != want {
+			t.Errorf("%s\nhave: %s\nwant: %s", f, have, want)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}})).Info("x", "g", g)
+	want = `{"level":"INFO","msg":"x","g":{"length":6,"period":60000000000,"secret":"[redacted]"}}` + "\n"
+	if have := buf.String(); have != want {
+		t.Errorf("\nhave: %s\nwant: %s", have, want)
+	}
+}
+
 func TestSecret(t *testing.T) {
 	one, two := otp.Secret(), otp.Secret()
 	if len(one) != 20 {
@@ -0,0 +1,625 @@
+// Package pskc reads and writes PSKC (Portable Symmetric Key Container)
+// documents, as defined in RFC6030.
+//
+// PSKC is commonly used by vendors to deliver the secrets of OATH hardware
+// tokens. Secrets may be encrypted with a pre-shared AES key or with a key
+// derived from a passphrase.
+package pskc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"zgo.at/otp"
+)
+
+// Key is a key from a PSKC document.
+type Key struct {
+	Key otp.Key
+
+	ID           string // Identifier of the key in the document.
+	Manufacturer string // Manufacturer of the device.
+	SerialNo     string // Serial number of the device.
+}
+
+// Options for Read() and Write().
+type Options struct {
+	// PreSharedKey is the AES key to decrypt or encrypt secrets with; it must
+	// be 16, 24, or 32 bytes.
+	PreSharedKey []byte
+
+	// KeyName is the name of the pre-shared key written to the document.
+	KeyName string
+
+	// Passphrase to derive the AES key from with PBKDF2.
+	Passphrase string
+
+	// Iterations for PBKDF2 when writing; the default is 100,000, and it
+	// can't be more than 10,000,000.
+	Iterations int
+}
+
+// Namespaces and algorithm identifiers.
+const (
+	nsPSKC  = "urn:ietf:params:xml:ns:keyprov:pskc"
+	nsDS    = "http://www.w3.org/2000/09/xmldsig#"
+	nsXenc  = "http://www.w3.org/2001/04/xmlenc#"
+	nsPKCS5 = "http://www.rsasecurity.com/rsalabs/pkcs/schemas/pkcs-5v2-0#"
+
+	algHOTP   = nsPSKC + ":hotp"
+	algTOTP   = nsPSKC + ":totp"
+	algPBKDF2 = nsPKCS5 + "pbkdf2"
+)
+
+// Limits for the PBKDF2 parameters in a document, so that a crafted document
+// can't make Read() spend minutes deriving a key or allocate a huge key.
+const (
+	maxIterations = 10_000_000
+	maxKeyLength  = 64
+)
+
+var (
+	ciphers = map[string]int{
+		nsXenc + "aes128-cbc": 16,
+		nsXenc + "aes192-cbc": 24,
+		nsXenc + "aes256-cbc": 32,
+	}
+	macs = map[string]func() hash.Hash{
+		nsDS + "hmac-sha1": sha1.New,
+		"http://www.w3.org/2001/04/xmldsig-more#hmac-sha256": sha256.New,
+		"http://www.w3.org/2001/04/xmldsig-more#hmac-sha512": sha512.New,
+	}
+	suites = map[string]crypto.Hash{
+		"SHA1":    crypto.SHA1,
+		"SHA-1":   crypto.SHA1,
+		"SHA256":  crypto.SHA256,
+		"SHA-256": crypto.SHA256,
+		"SHA512":  crypto.SHA512,
+		"SHA-512": crypto.SHA512,
+	}
+)
+
+type (
+	container struct {
+		XMLName       xml.Name
+		Version       string         `xml:"Version,attr"`
+		EncryptionKey *encryptionKey `xml:"EncryptionKey"`
+		MACMethod     *macMethod     `xml:"MACMethod"`
+		KeyPackages   []keyPackage   `xml:"KeyPackage"`
+	}
+	encryptionKey struct {
+		KeyName    string      `xml:"http://www.w3.org/2000/09/xmldsig# KeyName,omitempty"`
+		DerivedKey *derivedKey `xml:"http://www.w3.org/2009/xmlenc11# DerivedKey"`
+	}
+	derivedKey struct {
+		Method struct {
+			Algorithm string        `xml:"Algorithm,attr"`
+			Params    *pbkdf2Params `xml:"http://www.rsasecurity.com/rsalabs/pkcs/schemas/pkcs-5v2-0# PBKDF2-params"`
+		} `xml:"http://www.w3.org/2009/xmlenc11# KeyDerivationMethod"`
+		MasterKeyName string `xml:"http://www.w3.org/2009/xmlenc11# MasterKeyName,omitempty"`
+	}
+	pbkdf2Params struct {
+		Salt       string     `xml:"Salt>Specified"`
+		Iterations int        `xml:"IterationCount"`
+		KeyLength  int        `xml:"KeyLength"`
+		PRF        *algorithm `xml:"PRF"`
+	}
+	algorithm struct {
+		Algorithm string `xml:"Algorithm,attr"`
+	}
+	macMethod struct {
+		Algorithm string          `xml:"Algorithm,attr"`
+		MACKey    *encryptedValue `xml:"MACKey"`
+	}
+	encryptedValue struct {
+		Method     algorithm `xml:"http://www.w3.org/2001/04/xmlenc# EncryptionMethod"`
+		CipherData struct {
+			CipherValue string `xml:"http://www.w3.org/2001/04/xmlenc# CipherValue"`
+		} `xml:"http://www.w3.org/2001/04/xmlenc# CipherData"`
+	}
+	keyPackage struct {
+		DeviceInfo *deviceInfo `xml:"DeviceInfo"`
+		Key        *key        `xml:"Key"`
+	}
+	deviceInfo struct {
+		Manufacturer string `xml:"Manufacturer,omitempty"`
+		SerialNo     string `xml:"SerialNo,omitempty"`
+	}
+	key struct {
+		ID           string     `xml:"Id,attr"`
+		Algorithm    string     `xml:"Algorithm,attr"`
+		Issuer       string     `xml:"Issuer,omitempty"`
+		Params       *keyParams `xml:"AlgorithmParameters"`
+		FriendlyName string     `xml:"FriendlyName,omitempty"`
+		Data         *keyData   `xml:"Data"`
+		UserID       string     `xml:"UserId,omitempty"`
+	}
+	keyParams struct {
+		Suite          string          `xml:"Suite,omitempty"`
+		ResponseFormat *responseFormat `xml:"ResponseFormat"`
+	}
+	responseFormat struct {
+		Length   int    `xml:"Length,attr"`
+		Encoding string `xml:"Encoding,attr"`
+	}
+	keyData struct {
+		Secret       *value `xml:"Secret"`
+		Counter      *value `xml:"Counter"`
+		TimeInterval *value `xml:"TimeInterval"`
+	}
+	value struct {
+		PlainValue     string          `xml:"PlainValue,omitempty"`
+		EncryptedValue *encryptedValue `xml:"EncryptedValue"`
+		ValueMAC       string          `xml:"ValueMAC,omitempty"`
+	}
+)
+
+// Read all keys from a PSKC document.
+//
+// opt is only used for documents with encrypted values; the MAC of encrypted
+// values is verified if the document has one.
+//
+// Only HOTP and TOTP keys with decimal tokens are supported; an error is
+// returned for any other key.
+func Read(r io.Reader, opt Options) ([]Key, error) {
+	var c container
+	err := xml.NewDecoder(r).Decode(&c)
+	if err != nil {
+		return nil, fmt.Errorf("pskc.Read: %w", err)
+	}
+	if c.XMLName.Local != "KeyContainer" || c.XMLName.Space != nsPSKC {
+		return nil, fmt.Errorf("pskc.Read: not a PSKC document: root element is %q", c.XMLName.Local)
+	}
+	if c.Version != "1.0" {
+		return nil, fmt.Errorf("pskc.Read: unsupported version %q", c.Version)
+	}
+
+	d := &decrypter{c: c, opt: opt}
+	keys := make([]Key, 0, len(c.KeyPackages))
+	for i, p := range c.KeyPackages {
+		k, err := d.key(p)
+		if err != nil {
+			return nil, fmt.Errorf("pskc.Read: key %d: %w", i, err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+type decrypter struct {
+	c      container
+	opt    Options
+	encKey []byte
+	macKey []byte
+	mac    func() hash.Hash
+}
+
+func (d *decrypter) key(p keyPackage) (Key, error) {
+	if p.Key == nil {
+		return Key{}, errors.New("no Key element")
+	}
+	var (
+		pk = p.Key
+		k  = Key{ID: pk.ID, Key: otp.Key{Issuer: pk.Issuer, Account: pk.FriendlyName}}
+	)
+	if k.Key.Account == "" {
+		k.Key.Account = pk.UserID
+	}
+	if p.DeviceInfo != nil {
+		k.Manufacturer, k.SerialNo = p.DeviceInfo.Manufacturer, p.DeviceInfo.SerialNo
+	}
+
+	switch pk.Algorithm[strings.LastIndexAny(pk.Algorithm, ":#")+1:] {
+	case "hotp":
+		k.Key.Kind = otp.KindHOTP
+	case "totp":
+		k.Key.Kind, k.Key.Period = otp.KindTOTP, 30*time.Second
+	default:
+		return Key{}, fmt.Errorf("unsupported algorithm %q", pk.Algorithm)
+	}
+
+	k.Key.Algorithm, k.Key.Digits = crypto.SHA1, 6
+	if p := pk.Params; p != nil {
+		if p.Suite != "" {
+			var ok bool
+			k.Key.Algorithm, ok = suites[strings.TrimPrefix(strings.ToUpper(p.Suite), "HMAC-")]
+			if !ok {
+				return Key{}, fmt.Errorf("unsupported suite %q", p.Suite)
+			}
+		}
+		if f := p.ResponseFormat; f != nil {
+			if f.Encoding != "" && f.Encoding != "DECIMAL" {
+				return Key{}, fmt.Errorf("unsupported response encoding %q", f.Encoding)
+			}
+			if f.Length < 1 || f.Length > 10 {
+				return Key{}, fmt.Errorf("invalid response length %d", f.Length)
+			}
+			k.Key.Digits = f.Length
+		}
+	}
+
+	if pk.Data == nil || pk.Data.Secret == nil {
+		return Key{}, errors.New("secret is missing")
+	}
+	var err error
+	k.Key.Secret, err = d.value(pk.Data.Secret)
+	if err != nil {
+		return Key{}, fmt.Errorf("secret: %w", err)
+	}
+	if len(k.Key.Secret) == 0 {
+		return Key{}, errors.New("secret is empty")
+	}
+	if pk.Data.Counter != nil {
+		k.Key.Counter, err = d.int(pk.Data.Counter)
+		if err != nil {
+			return Key{}, fmt.Errorf("counter: %w", err)
+		}
+	}
+	if pk.Data.TimeInterval != nil {
+		n, err := d.int(pk.Data.TimeInterval)
+		if err != nil || n == 0 || n > 1<<32 {
+			return Key{}, errors.New("invalid time interval")
+		}
+		k.Key.Period = time.Duration(n) * time.Second
+	}
+	return k, nil
+}
+
+// int decodes an integer value; encrypted integers are big-endian.
+func (d *decrypter) int(v *value) (uint64, error) {
+	if v.EncryptedValue == nil {
+		return strconv.ParseUint(strings.TrimSpace(v.PlainValue), 10, 64)
+	}
+	b, err := d.value(v)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) > 8 {
+		return 0, errors.New("integer is longer than 8 bytes")
+	}
+	return binary.BigEndian.Uint64(append(make([]byte, 8-len(b)), b...)), nil
+}
+
+func (d *decrypter) value(v *value) ([]byte, error) {
+	if v.EncryptedValue == nil {
+		return decodeBase64(v.PlainValue)
+	}
+
+	ct, err := decodeBase64(v.EncryptedValue.CipherData.CipherValue)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.init(); err != nil {
+		return nil, err
+	}
+	switch {
+	case v.ValueMAC != "":
+		want, err := decodeBase64(v.ValueMAC)
+		if err != nil {
+			return nil, fmt.Errorf("ValueMAC: %w", err)
+		}
+		h := hmac.New(d.mac, d.macKey)
+		h.Write(ct)
+		if !hmac.Equal(h.Sum(nil), want) {
+			return nil, errors.New("MAC doesn't match: wrong key or corrupt data")
+		}
+	case d.c.MACMethod != nil:
+		return nil, errors.New("ValueMAC is missing")
+	}
+	return decrypt(v.EncryptedValue.Method.Algorithm, d.encKey, ct)
+}
+
+// init sets up the encryption and MAC keys.
+func (d *decrypter) init() error {
+	if d.encKey != nil {
+		return nil
+	}
+
+	ek := d.c.EncryptionKey
+	switch {
+	case ek != nil && ek.DerivedKey != nil:
+		dk := ek.DerivedKey
+		if dk.Method.Algorithm != algPBKDF2 || dk.Method.Params == nil {
+			return fmt.Errorf("unsupported key derivation %q", dk.Method.Algorithm)
+		}
+		if d.opt.Passphrase == "" {
+			return errors.New("document is encrypted with a passphrase, but Options.Passphrase is empty")
+		}
+		p := dk.Method.Params
+		salt, err := decodeBase64(p.Salt)
+		if err != nil {
+			return fmt.Errorf("PBKDF2 salt: %w", err)
+		}
+		prf := sha1.New
+		if p.PRF != nil && p.PRF.Algorithm != "" {
+			var ok bool
+			prf, ok = macs[p.PRF.Algorithm]
+			if !ok {
+				return fmt.Errorf("unsupported PBKDF2 PRF %q", p.PRF.Algorithm)
+			}
+		}
+		switch {
+		case p.Iterations < 1:
+			return fmt.Errorf("PBKDF2 IterationCount %d must be at least 1", p.Iterations)
+		case p.Iterations > maxIterations:
+			return fmt.Errorf("PBKDF2 IterationCount %d is larger than the maximum of %d", p.Iterations, maxIterations)
+		}
+		if p.KeyLength == 0 {
+			p.KeyLength = 16
+		}
+		if p.KeyLength > maxKeyLength {
+			return fmt.Errorf("PBKDF2 KeyLength %d is larger than the maximum of %d", p.KeyLength, maxKeyLength)
+		}
+		d.encKey, err = pbkdf2.Key(prf, d.opt.Passphrase, salt, p.Iterations, p.KeyLength)
+		if err != nil {
+			return fmt.Errorf("PBKDF2: %w", err)
+		}
+	default:
+		if len(d.opt.PreSharedKey) == 0 {
+			return errors.New("document is encrypted with a pre-shared key, but Options.PreSharedKey is empty")
+		}
+		d.encKey = d.opt.PreSharedKey
+	}
+
+	// Documents without a MACMethod use the encryption key, as in earlier
+	// drafts of the RFC.
+	d.mac, d.macKey = sha1.New, d.encKey
+	if m := d.c.MACMethod; m != nil {
+		var ok bool
+		d.mac, ok = macs[m.Algorithm]
+		if !ok {
+			return fmt.Errorf("unsupported MAC algorithm %q", m.Algorithm)
+		}
+		if m.MACKey == nil {
+			return errors.New("MACKey is missing")
+		}
+		ct, err := decodeBase64(m.MACKey.CipherData.CipherValue)
+		if err != nil {
+			return fmt.Errorf("MACKey: %w", err)
+		}
+		d.macKey, err = decrypt(m.MACKey.Method.Algorithm, d.encKey, ct)
+		if err != nil {
+			return fmt.Errorf("MACKey: %w", err)
+		}
+	}
+	return nil
+}
+
+// MarshalXML writes the parameters without a namespace like in the RFC;
+// encoding/xml would otherwise put them in the pkcs5 namespace.
+func (p pbkdf2Params) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	elem := func(name string, v any) error {
+		return e.EncodeElement(v, xml.StartElement{
+			Name: xml.Name{Local: name},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: ""}},
+		})
+	}
+
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	err = elem("Salt", struct {
+		Specified string `xml:"Specified"`
+	}{p.Salt})
+	if err != nil {
+		return err
+	}
+	err = elem("IterationCount", p.Iterations)
+	if err != nil {
+		return err
+	}
+	err = elem("KeyLength", p.KeyLength)
+	if err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Write keys as a PSKC document.
+//
+// Secrets are encrypted if opt.PreSharedKey or opt.Passphrase is set; the
+// pre-shared key selects AES-128, AES-192, or AES-256 by its length, and a
+// passphrase uses PBKDF2 with AES-128. Encrypted secrets are authenticated with
+// HMAC-SHA1 and a random MAC key.
+//
+// Only HOTP and TOTP keys are supported.
+func Write(w io.Writer, keys []Key, opt Options) error {
+	c := container{XMLName: xml.Name{Space: nsPSKC, Local: "KeyContainer"}, Version: "1.0"}
+
+	var (
+		encKey []byte
+		encAlg string
+	)
+	switch {
+	case len(opt.PreSharedKey) > 0 && opt.Passphrase != "":
+		return errors.New("pskc.Write: can't use both PreSharedKey and Passphrase")
+	case len(opt.PreSharedKey) > 0:
+		encKey = opt.PreSharedKey
+		encAlg = fmt.Sprintf("%saes%d-cbc", nsXenc, len(encKey)*8)
+		if _, ok := ciphers[encAlg]; !ok {
+			return fmt.Errorf("pskc.Write: PreSharedKey must be 16, 24, or 32 bytes, not %d", len(encKey))
+		}
+		c.EncryptionKey = &encryptionKey{KeyName: opt.KeyName}
+	case opt.Passphrase != "":
+		switch {
+		case opt.Iterations == 0:
+			opt.Iterations = 100_000
+		case opt.Iterations < 1:
+			return fmt.Errorf("pskc.Write: Iterations %d must be at least 1", opt.Iterations)
+		case opt.Iterations > maxIterations:
+			return fmt.Errorf("pskc.Write: Iterations %d is larger than the maximum of %d", opt.Iterations, maxIterations)
+		}
+		salt := make([]byte, 16)
+		_, _ = rand.Read(salt) // Documented as never returning an error
+		var err error
+		encKey, err = pbkdf2.Key(sha1.New, opt.Passphrase, salt, opt.Iterations, 16)
+		if err != nil {
+			return fmt.Errorf("pskc.Write: %w", err)
+		}
+		encAlg = nsXenc + "aes128-cbc"
+
+		dk := &derivedKey{MasterKeyName: opt.KeyName}
+		dk.Method.Algorithm = algPBKDF2
+		dk.Method.Params = &pbkdf2Params{
+			Salt:       base64.StdEncoding.EncodeToString(salt),
+			Iterations: opt.Iterations,
+			KeyLength:  16,
+		}
+		c.EncryptionKey = &encryptionKey{DerivedKey: dk}
+	}
+
+	var macKey []byte
+	if encKey != nil {
+		macKey = make([]byte, 20)
+		_, _ = rand.Read(macKey)
+		c.MACMethod = &macMethod{
+			Algorithm: nsDS + "hmac-sha1",
+			MACKey:    encrypt(encAlg, encKey, macKey),
+		}
+	}
+
+	for i, k := range keys {
+		pk := key{
+			ID:           k.ID,
+			Issuer:       k.Key.Issuer,
+			FriendlyName: k.Key.Account,
+			Params:       &keyParams{},
+			Data:         &keyData{},
+		}
+		if pk.ID == "" {
+			pk.ID = strconv.Itoa(i + 1)
+		}
+
+		switch k.Key.Kind {
+		case otp.KindTOTP, "":
+			pk.Algorithm = algTOTP
+			if !k.Key.T0.IsZero() && k.Key.T0.Unix() != 0 {
+				return fmt.Errorf("pskc.Write: key %d: T0 not supported", i)
+			}
+			if k.Key.Period != 0 {
+				if k.Key.Period%time.Second != 0 {
+					return fmt.Errorf("pskc.Write: key %d: period %s is not a whole number of seconds", i, k.Key.Period)
+				}
+				pk.Data.TimeInterval = &value{PlainValue: strconv.FormatInt(int64(k.Key.Period/time.Second), 10)}
+			}
+		case otp.KindHOTP:
+			pk.Algorithm = algHOTP
+			pk.Data.Counter = &value{PlainValue: strconv.FormatUint(k.Key.Counter, 10)}
+		default:
+			return fmt.Errorf("pskc.Write: key %d: kind %q not supported", i, k.Key.Kind)
+		}
+
+		switch k.Key.Algorithm {
+		case 0, crypto.SHA1:
+		case crypto.SHA256, crypto.SHA512:
+			pk.Params.Suite = "HMAC-" + strings.ReplaceAll(k.Key.Algorithm.String(), "-", "")
+		default:
+			return fmt.Errorf("pskc.Write: key %d: algorithm %s not supported", i, k.Key.Algorithm)
+		}
+		pk.Params.ResponseFormat = &responseFormat{Length: k.Key.Digits, Encoding: "DECIMAL"}
+		if k.Key.Digits == 0 {
+			pk.Params.ResponseFormat.Length = 6
+		}
+
+		if len(k.Key.Secret) == 0 {
+			return fmt.Errorf("pskc.Write: key %d: secret is empty", i)
+		}
+		if encKey == nil {
+			pk.Data.Secret = &value{PlainValue: base64.StdEncoding.EncodeToString(k.Key.Secret)}
+		} else {
+			ev := encrypt(encAlg, encKey, k.Key.Secret)
+			ct, _ := base64.StdEncoding.DecodeString(ev.CipherData.CipherValue)
+			h := hmac.New(sha1.New, macKey)
+			h.Write(ct)
+			pk.Data.Secret = &value{EncryptedValue: ev, ValueMAC: base64.StdEncoding.EncodeToString(h.Sum(nil))}
+		}
+
+		p := keyPackage{Key: &pk}
+		if k.Manufacturer != "" || k.SerialNo != "" {
+			p.DeviceInfo = &deviceInfo{Manufacturer: k.Manufacturer, SerialNo: k.SerialNo}
+		}
+		c.KeyPackages = append(c.KeyPackages, p)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return fmt.Errorf("pskc.Write: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	err = enc.Encode(c)
+	if err != nil {
+		return fmt.Errorf("pskc.Write: %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	if err != nil {
+		return fmt.Errorf("pskc.Write: %w", err)
+	}
+	return nil
+}
+
+// decrypt ct with AES-CBC; the IV is prepended to ct and the plaintext is
+// padded with PKCS#7.
+func decrypt(alg string, key, ct []byte) ([]byte, error) {
+	size, ok := ciphers[alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encryption algorithm %q", alg)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("encryption key is %d bytes, but %s needs %d bytes", len(key), alg, size)
+	}
+	if len(ct) < 2*aes.BlockSize || len(ct)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid ciphertext length")
+	}
+
+	b, _ := aes.NewCipher(key) // Key size checked above.
+	pt := make([]byte, len(ct)-aes.BlockSize)
+	cipher.NewCBCDecrypter(b, ct[:aes.BlockSize]).CryptBlocks(pt, ct[aes.BlockSize:])
+
+	pad := int(pt[len(pt)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(pt[len(pt)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, errors.New("invalid padding: wrong key or corrupt data")
+	}
+	return pt[:len(pt)-pad], nil
+}
+
+func encrypt(alg string, key, pt []byte) *encryptedValue {
+	pad := aes.BlockSize - len(pt)%aes.BlockSize
+	ct := make([]byte, aes.BlockSize, aes.BlockSize+len(pt)+pad)
+	_, _ = rand.Read(ct)
+	ct = append(append(ct, pt...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	b, _ := aes.NewCipher(key) // Key size checked by caller.
+	cipher.NewCBCEncrypter(b, ct[:aes.BlockSize]).CryptBlocks(ct[aes.BlockSize:], ct[aes.BlockSize:])
+
+	ev := &encryptedValue{Method: algorithm{alg}}
+	ev.CipherData.CipherValue = base64.StdEncoding.EncodeToString(ct)
+	return ev
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,418 @@
+package pskc_test
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/pskc"
+)
+
+// Examples from RFC6030; all use the secret "12345678901234567890".
+const (
+	// Figure 2
+	rfcMinimal = `<?xml version="1.0" encoding="UTF-8"?>
+<KeyContainer Version="1.0"
+    Id="exampleID1"
+    xmlns="urn:ietf:params:xml:ns:keyprov:pskc">
+    <KeyPackage>
+        <Key Id="12345678"
+            Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp">
+            <Issuer>Issuer-A</Issuer>
+            <Data>
+                <Secret>
+                    <PlainValue>MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=
+                    </PlainValue>
+                </Secret>
+            </Data>
+        </Key>
+    </KeyPackage>
+</KeyContainer>`
+
+	// Figure 3
+	rfcDevice = `<?xml version="1.0" encoding="UTF-8"?>
+<KeyContainer Version="1.0"
+    Id="exampleID1"
+    xmlns="urn:ietf:params:xml:ns:keyprov:pskc">
+    <KeyPackage>
+        <DeviceInfo>
+            <Manufacturer>Manufacturer</Manufacturer>
+            <SerialNo>987654321</SerialNo>
+            <UserId>DC=example-bank,DC=net</UserId>
+        </DeviceInfo>
+        <CryptoModuleInfo>
+            <Id>CM_ID_001</Id>
+        </CryptoModuleInfo>
+        <Key Id="12345678"
+            Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp">
+            <Issuer>Issuer</Issuer>
+            <AlgorithmParameters>
+                <ResponseFormat Length="8" Encoding="DECIMAL"/>
+            </AlgorithmParameters>
+            <Data>
+                <Secret>
+                    <PlainValue>MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=
+                    </PlainValue>
+                </Secret>
+                <Counter>
+                    <PlainValue>0</PlainValue>
+                </Counter>
+            </Data>
+            <UserId>UID=jsmith,DC=example-bank,DC=net</UserId>
+        </Key>
+    </KeyPackage>
+</KeyContainer>`
+
+	// Figure 6; the pre-shared key is 12345678901234567890123456789012 (hex).
+	rfcPreShared = `<?xml version="1.0" encoding="UTF-8"?>
+<KeyContainer Version="1.0"
+    xmlns="urn:ietf:params:xml:ns:keyprov:pskc"
+    xmlns:ds="http://www.w3.org/2000/09/xmldsig#"
+    xmlns:xenc="http://www.w3.org/2001/04/xmlenc#">
+    <EncryptionKey>
+        <ds:KeyName>Pre-shared-key</ds:KeyName>
+    </EncryptionKey>
+    <MACMethod Algorithm="http://www.w3.org/2000/09/xmldsig#hmac-sha1">
+        <MACKey>
+            <xenc:EncryptionMethod
+            Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+            <xenc:CipherData>
+                <xenc:CipherValue>
+    ESIzRFVmd4iZABEiM0RVZgKn6WjLaTC1sbeBMSvIhRejN9vJa2BOlSaMrR7I5wSX
+                </xenc:CipherValue>
+            </xenc:CipherData>
+        </MACKey>
+    </MACMethod>
+    <KeyPackage>
+        <DeviceInfo>
+            <Manufacturer>Manufacturer</Manufacturer>
+            <SerialNo>987654321</SerialNo>
+        </DeviceInfo>
+        <CryptoModuleInfo>
+            <Id>CM_ID_001</Id>
+        </CryptoModuleInfo>
+        <Key Id="12345678"
+            Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp">
+            <Issuer>Issuer</Issuer>
+            <AlgorithmParameters>
+                <ResponseFormat Length="8" Encoding="DECIMAL"/>
+            </AlgorithmParameters>
+            <Data>
+                <Secret>
+                    <EncryptedValue>
+                        <xenc:EncryptionMethod
+            Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+                        <xenc:CipherData>
+                            <xenc:CipherValue>
+    AAECAwQFBgcICQoLDA0OD+cIHItlB3Wra1DUpxVvOx2lef1VmNPCMl8jwZqIUqGv
+                            </xenc:CipherValue>
+                        </xenc:CipherData>
+                    </EncryptedValue>
+                    <ValueMAC>Su+NvtQfmvfJzF6bmQiJqoLRExc=
+                    </ValueMAC>
+                </Secret>
+                <Counter>
+                    <PlainValue>0</PlainValue>
+                </Counter>
+            </Data>
+        </Key>
+    </KeyPackage>
+</KeyContainer>`
+
+	// Figure 7; the passphrase is "qwerty".
+	rfcPBKDF2 = `<?xml version="1.0" encoding="UTF-8"?>
+<pskc:KeyContainer
+  xmlns:pskc="urn:ietf:params:xml:ns:keyprov:pskc"
+  xmlns:xenc11="http://www.w3.org/2009/xmlenc11#"
+  xmlns:pkcs5=
+  "http://www.rsasecurity.com/rsalabs/pkcs/schemas/pkcs-5v2-0#"
+  xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" Version="1.0">
+    <pskc:EncryptionKey>
+        <xenc11:DerivedKey>
+            <xenc11:KeyDerivationMethod
+              Algorithm=
+"http://www.rsasecurity.com/rsalabs/pkcs/schemas/pkcs-5v2-0#pbkdf2">
+                <pkcs5:PBKDF2-params>
+                    <Salt>
+                        <Specified>Ej7/PEpyEpw=</Specified>
+                    </Salt>
+                    <IterationCount>1000</IterationCount>
+                    <KeyLength>16</KeyLength>
+                    <PRF/>
+                </pkcs5:PBKDF2-params>
+            </xenc11:KeyDerivationMethod>
+            <xenc:ReferenceList>
+                <xenc:DataReference URI="#ED"/>
+            </xenc:ReferenceList>
+            <xenc11:MasterKeyName>My Password 1</xenc11:MasterKeyName>
+        </xenc11:DerivedKey>
+    </pskc:EncryptionKey>
+    <pskc:MACMethod
+        Algorithm="http://www.w3.org/2000/09/xmldsig#hmac-sha1">
+        <pskc:MACKey>
+            <xenc:EncryptionMethod
+            Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+            <xenc:CipherData>
+                <xenc:CipherValue>
+2GTTnLwM3I4e5IO5FkufoOEiOhNj91fhKRQBtBJYluUDsPOLTfUvoU2dStyOwYZx
+                </xenc:CipherValue>
+            </xenc:CipherData>
+        </pskc:MACKey>
+    </pskc:MACMethod>
+    <pskc:KeyPackage>
+        <pskc:DeviceInfo>
+            <pskc:Manufacturer>TokenVendorAcme</pskc:Manufacturer>
+            <pskc:SerialNo>987654321</pskc:SerialNo>
+        </pskc:DeviceInfo>
+        <pskc:CryptoModuleInfo>
+            <pskc:Id>CM_ID_001</pskc:Id>
+        </pskc:CryptoModuleInfo>
+        <pskc:Key Algorithm=
+        "urn:ietf:params:xml:ns:keyprov:pskc:hotp" Id="123456">
+            <pskc:Issuer>Example-Issuer</pskc:Issuer>
+            <pskc:AlgorithmParameters>
+                <pskc:ResponseFormat Length="8" Encoding="DECIMAL"/>
+            </pskc:AlgorithmParameters>
+            <pskc:Data>
+                <pskc:Secret>
+                <pskc:EncryptedValue Id="ED">
+                    <xenc:EncryptionMethod
+                        Algorithm=
+"http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+                        <xenc:CipherData>
+                            <xenc:CipherValue>
+      oTvo+S22nsmS2Z/RtcoF8Hfh+jzMe0RkiafpoDpnoZTjPYZu6V+A4aEn032yCr4f
+                        </xenc:CipherValue>
+                    </xenc:CipherData>
+                    </pskc:EncryptedValue>
+                    <pskc:ValueMAC>LP6xMvjtypbfT9PdkJhBZ+D6O4w=
+                    </pskc:ValueMAC>
+                </pskc:Secret>
+            </pskc:Data>
+        </pskc:Key>
+    </pskc:KeyPackage>
+</pskc:KeyContainer>`
+)
+
+var (
+	secret = []byte("12345678901234567890")
+	psk, _ = hex.DecodeString("12345678901234567890123456789012")
+)
+
+func TestRead(t *testing.T) {
+	hotp := func(issuer string, digits int) otp.Key {
+		return otp.Key{Kind: otp.KindHOTP, Secret: secret, Issuer: issuer, Algorithm: crypto.SHA1, Digits: digits}
+	}
+
+	tests := []struct {
+		doc     string
+		opt     pskc.Options
+		want    []pskc.Key
+		wantErr string
+	}{
+		{rfcMinimal, pskc.Options{}, []pskc.Key{{Key: hotp("Issuer-A", 6), ID: "12345678"}}, ""},
+		{rfcDevice, pskc.Options{}, []pskc.Key{{
+			Key: func() otp.Key { k := hotp("Issuer", 8); k.Account = "UID=jsmith,DC=example-bank,DC=net"; return k }(),
+			ID:  "12345678", Manufacturer: "Manufacturer", SerialNo: "987654321",
+		}}, ""},
+		{rfcPreShared, pskc.Options{PreSharedKey: psk}, []pskc.Key{{
+			Key: hotp("Issuer", 8), ID: "12345678", Manufacturer: "Manufacturer", SerialNo: "987654321",
+		}}, ""},
+		{rfcPBKDF2, pskc.Options{Passphrase: "qwerty"}, []pskc.Key{{
+			Key: hotp("Example-Issuer", 8), ID: "123456", Manufacturer: "TokenVendorAcme", SerialNo: "987654321",
+		}}, ""},
+
+		{rfcPreShared, pskc.Options{}, nil, "Options.PreSharedKey is empty"},
+		{rfcPreShared, pskc.Options{PreSharedKey: make([]byte, 16)}, nil, "invalid padding"},
+		{rfcPreShared, pskc.Options{PreSharedKey: make([]byte, 32)}, nil, "needs 16 bytes"},
+		{rfcPBKDF2, pskc.Options{}, nil, "Options.Passphrase is empty"},
+		{rfcPBKDF2, pskc.Options{Passphrase: "QWERTY"}, nil, "MACKey: invalid padding"},
+		{strings.Replace(rfcPBKDF2, "<IterationCount>1000<", "<IterationCount>10000001<", 1), pskc.Options{Passphrase: "qwerty"},
+			nil, "PBKDF2 IterationCount 10000001 is larger than the maximum of 10000000"},
+		{strings.Replace(rfcPBKDF2, "<IterationCount>1000<", "<IterationCount>0<", 1), pskc.Options{Passphrase: "qwerty"},
+			nil, "PBKDF2 IterationCount 0 must be at least 1"},
+		{strings.Replace(rfcPBKDF2, "<IterationCount>1000<", "<IterationCount>-5<", 1), pskc.Options{Passphrase: "qwerty"},
+			nil, "PBKDF2 IterationCount -5 must be at least 1"},
+		{strings.Replace(rfcPBKDF2, "<KeyLength>16<", "<KeyLength>1000000000<", 1), pskc.Options{Passphrase: "qwerty"},
+			nil, "PBKDF2 KeyLength 1000000000 is larger than the maximum of 64"},
+		{strings.Replace(rfcPreShared, "Su+Nvt", "Su+NVT", 1), pskc.Options{PreSharedKey: psk}, nil, "MAC doesn't match"},
+		{strings.Replace(rfcPreShared, "<ValueMAC>Su+NvtQfmvfJzF6bmQiJqoLRExc=\n                    </ValueMAC>", "", 1),
+			pskc.Options{PreSharedKey: psk}, nil, "ValueMAC is missing"},
+		{strings.Replace(rfcMinimal, "pskc:hotp", "pskc:pin", 1), pskc.Options{}, nil, `key 0: unsupported algorithm "urn:ietf:params:xml:ns:keyprov:pskc:pin"`},
+		{strings.Replace(rfcDevice, `"DECIMAL"`, `"ALPHANUMERIC"`, 1), pskc.Options{}, nil, `unsupported response encoding "ALPHANUMERIC"`},
+		{strings.Replace(rfcMinimal, `Version="1.0"`, `Version="2.0"`, 1), pskc.Options{}, nil, `unsupported version "2.0"`},
+		{strings.Replace(rfcMinimal, "keyprov:pskc\"", "keyprov:other\"", 1), pskc.Options{}, nil, "not a PSKC document"},
+		{"<KeyContainer", pskc.Options{}, nil, "XML syntax error"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := pskc.Read(strings.NewReader(tt.doc), tt.opt)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTOTP(t *testing.T) {
+	doc := `<KeyContainer Version="1.0" xmlns="urn:ietf:params:xml:ns:keyprov:pskc">
+		<KeyPackage>
+			<Key Id="1" Algorithm="urn:ietf:params:xml:ns:keyprov:pskc#totp">
+				<AlgorithmParameters>
+					<Suite>HMAC-SHA256</Suite>
+					<ResponseFormat Length="8" Encoding="DECIMAL"/>
+				</AlgorithmParameters>
+				<Data>
+					<Secret><PlainValue>MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=</PlainValue></Secret>
+					<TimeInterval><PlainValue>60</PlainValue></TimeInterval>
+				</Data>
+				<FriendlyName>alice</FriendlyName>
+			</Key>
+		</KeyPackage>
+	</KeyContainer>`
+
+	keys, err := pskc.Read(strings.NewReader(doc), pskc.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := otp.Key{
+		Kind: otp.KindTOTP, Secret: []byte("12345678901234567890123456789012"), Account: "alice",
+		Algorithm: crypto.SHA256, Digits: 8, Period: time.Minute,
+	}
+	if !reflect.DeepEqual(keys[0].Key, want) {
+		t.Fatalf("\nhave: %#v\nwant: %#v", keys[0].Key, want)
+	}
+
+	// RFC6238 appendix B: T=59 with a 30s period is T=119 with a 60s period.
+	g, err := keys[0].Key.Generator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := g.TokenAt(time.Unix(119, 0)); err != nil || have != "46119246" {
+		t.Errorf("\nhave: %q\nwant: %q", have, "46119246")
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	keys, err := pskc.Read(strings.NewReader(rfcPBKDF2), pskc.Options{Passphrase: "qwerty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := keys[0].Key.Generator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RFC4226 appendix D, with 8 digits.
+	for i, want := range []string{"84755224", "94287082", "37359152"} {
+		if have := g.Token(i); have != want {
+			t.Errorf("%d\nhave: %q\nwant: %q", i, have, want)
+		}
+	}
+}
+
+func TestKeyJSON(t *testing.T) {
+	k := pskc.Key{ID: "id1", SerialNo: "SN123", Manufacturer: "Manufacturer",
+		Key: otp.Key{Kind: otp.KindHOTP, Secret: secret, Account: "me"}}
+	j, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have pskc.Key
+	if err := json.Unmarshal(j, &have); err != nil {
+		t.Fatal(err)
+	}
+	if have.ID != k.ID || have.SerialNo != k.SerialNo || have.Manufacturer != k.Manufacturer || have.Key.Account != k.Key.Account {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, k)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	keys := []pskc.Key{
+		{Key: otp.Key{Kind: otp.KindHOTP, Secret: secret, Issuer: "Example", Account: "alice",
+			Algorithm: crypto.SHA1, Digits: 6, Counter: 42}, ID: "hotp-1", Manufacturer: "Acme", SerialNo: "123"},
+		{Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: "bob",
+			Algorithm: crypto.SHA512, Digits: 8, Period: time.Minute}, ID: "totp-1"},
+		{Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, ID: "totp-2"},
+	}
+
+	tests := []struct {
+		name string
+		opt  pskc.Options
+	}{
+		{"plain", pskc.Options{}},
+		{"aes128", pskc.Options{PreSharedKey: psk, KeyName: "Pre-shared-key"}},
+		{"aes256", pskc.Options{PreSharedKey: append(psk, psk...)}},
+		{"pbkdf2", pskc.Options{Passphrase: "qwerty", Iterations: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := pskc.Write(&buf, keys, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.opt.PreSharedKey != nil || tt.opt.Passphrase != "" {
+				if bytes.Contains(buf.Bytes(), []byte("PlainValue>MTIz")) {
+					t.Fatalf("secret not encrypted:\n%s", buf.String())
+				}
+			}
+
+			have, err := pskc.Read(&buf, tt.opt)
+			if err != nil {
+				t.Fatalf("%s\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(have, keys) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, keys)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		keys    []pskc.Key
+		opt     pskc.Options
+		wantErr string
+	}{
+		{[]pskc.Key{{Key: otp.Key{Kind: otp.KindSteam, Secret: secret}}}, pskc.Options{}, `kind "steam" not supported`},
+		{[]pskc.Key{{Key: otp.Key{Kind: otp.KindTOTP}}}, pskc.Options{}, "secret is empty"},
+		{[]pskc.Key{{Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, Algorithm: crypto.MD5}}}, pskc.Options{}, "algorithm MD5 not supported"},
+		{[]pskc.Key{{Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, T0: time.Unix(1, 0)}}}, pskc.Options{}, "T0 not supported"},
+		{[]pskc.Key{{Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, Period: 1500 * time.Millisecond}}}, pskc.Options{}, "whole number of seconds"},
+		{nil, pskc.Options{PreSharedKey: psk[:10]}, "must be 16, 24, or 32 bytes, not 10"},
+		{nil, pskc.Options{PreSharedKey: psk, Passphrase: "x"}, "can't use both"},
+		{nil, pskc.Options{Passphrase: "x", Iterations: -1}, "Iterations -1 must be at least 1"},
+		{nil, pskc.Options{Passphrase: "x", Iterations: 10_000_001}, "Iterations 10000001 is larger than the maximum of 10000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			err := pskc.Write(new(bytes.Buffer), tt.keys, tt.opt)
+			if !errorContains(err, tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func errorContains(err error, want string) bool {
+	if err == nil {
+		return want == ""
+	}
+	if want == "" {
+		return false
+	}
+	return strings.Contains(err.Error(), want)
+}
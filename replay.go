@@ -0,0 +1,90 @@
+package otp
+
+import (
+	"sync"
+	"time"
+)
+
+// UsedTokenStore records the last accepted counter for an account, to prevent
+// the same token from being accepted twice.
+type UsedTokenStore interface {
+	// Use records counter as the last accepted counter for account.
+	//
+	// It returns false if counter is at or before the last counter recorded for
+	// account, in which case nothing is recorded. This must be atomic.
+	Use(account string, counter uint64) (bool, error)
+}
+
+// VerifyOnce verifies a token, like VerifyDetailed(), and records the counter
+// that matched in store.
+//
+// Returns ErrReplayed if the token matched but its counter is at or before the
+// last counter accepted for account. RFC6238 section 5.2 says a verifier must
+// not accept the same token twice; this also rejects older tokens from the
+// window once a newer one was used.
+func (g Generator) VerifyOnce(store UsedTokenStore, account, token string, offset int) (VerifyResult, error) {
+	r, err := g.VerifyDetailed(token, offset)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	ok, err := store.Use(account, r.Counter)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if !ok {
+		return VerifyResult{}, ErrReplayed
+	}
+	return r, nil
+}
+
+// MemoryUsedTokenStore stores used tokens in memory.
+//
+// Entries are removed ttl after they were last used. The ttl should be at least
+// as long as the verification window; e.g. 90 seconds for a TOTP step of 30
+// seconds and an offset of 1.
+type MemoryUsedTokenStore struct {
+	// Now returns the current time. It uses time.Now() if nil. This must be
+	// set before the store is used.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	ttl   time.Duration
+	m     map[string]usedToken
+	purge time.Time
+}
+
+type usedToken struct {
+	counter uint64
+	expires time.Time
+}
+
+var _ UsedTokenStore = &MemoryUsedTokenStore{}
+
+// NewMemoryUsedTokenStore creates a new in-memory store for used tokens.
+func NewMemoryUsedTokenStore(ttl time.Duration) *MemoryUsedTokenStore {
+	return &MemoryUsedTokenStore{ttl: ttl, m: make(map[string]usedToken)}
+}
+
+func (m *MemoryUsedTokenStore) Use(account string, counter uint64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if m.Now != nil {
+		now = m.Now()
+	}
+	if now.After(m.purge) {
+		for k, u := range m.m {
+			if now.After(u.expires) {
+				delete(m.m, k)
+			}
+		}
+		m.purge = now.Add(m.ttl)
+	}
+
+	if u, ok := m.m[account]; ok && now.Before(u.expires) && counter <= u.counter {
+		return false, nil
+	}
+	m.m[account] = usedToken{counter: counter, expires: now.Add(m.ttl)}
+	return true, nil
+}
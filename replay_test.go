@@ -0,0 +1,92 @@
+package otp_test
+
+import (
+	"crypto/sha1"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+)
+
+func TestVerifyOnce(t *testing.T) {
+	var (
+		store = otp.NewMemoryUsedTokenStore(time.Minute)
+		o     = otp.New(secret, 8, sha1.New, otp.TOTP(0, func() time.Time {
+			return time.Date(2005, 3, 18, 1, 58, 29, 0, time.UTC)
+		}))
+	)
+
+	tests := []struct {
+		account string
+		token   string
+		wantErr error
+	}{
+		{"a", o.Token(-1), nil},
+		{"a", o.Token(-1), otp.ErrReplayed},
+		{"b", o.Token(-1), nil},
+		{"a", o.Token(1), nil},
+		{"a", o.Token(0), otp.ErrReplayed}, // Before last accepted counter.
+		{"a", o.Token(1), otp.ErrReplayed},
+		{"b", o.Token(0), nil},
+		{"a", "XXX", otp.ErrInvalidFormat},
+		{"a", o.Token(2), otp.ErrNoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := o.VerifyOnce(store, tt.account, tt.token, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMemoryUsedTokenStoreTTL(t *testing.T) {
+	var (
+		now   = time.Date(2005, 3, 18, 1, 58, 29, 0, time.UTC)
+		store = otp.NewMemoryUsedTokenStore(time.Minute)
+	)
+	store.Now = func() time.Time { return now }
+
+	if ok, _ := store.Use("a", 5); !ok {
+		t.Fatal("first use rejected")
+	}
+	now = now.Add(59 * time.Second)
+	if ok, _ := store.Use("a", 5); ok {
+		t.Fatal("second use accepted")
+	}
+	now = now.Add(2 * time.Second)
+	if ok, _ := store.Use("a", 5); !ok {
+		t.Fatal("rejected after ttl")
+	}
+}
+
+func TestVerifyOnceConcurrent(t *testing.T) {
+	var (
+		store = otp.NewMemoryUsedTokenStore(time.Minute)
+		o     = otp.New(secret, 6, sha1.New, otp.TOTP(0, func() time.Time {
+			return time.Date(2005, 3, 18, 1, 58, 29, 0, time.UTC)
+		}))
+		tok = o.Token(0)
+		wg  sync.WaitGroup
+		n   atomic.Int32
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := o.VerifyOnce(store, "user", tok, 1); err == nil {
+				n.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n.Load() != 1 {
+		t.Errorf("token accepted %d times", n.Load())
+	}
+}
@@ -0,0 +1,147 @@
+package otp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Sealer encrypts secrets for storage, so that a leaked database doesn't leak
+// the secrets.
+//
+// Secrets are encrypted with AES-GCM under a key-encryption key (KEK). Every
+// KEK has a numeric ID, which is stored in the sealed secret so that the KEK
+// can be rotated: new secrets are always sealed with the current KEK, and older
+// KEKs are only used to open secrets. Use Reseal() to re-encrypt secrets with
+// the current KEK, after which the old KEK can be removed.
+//
+// The sealed secret is bound to a user ID, so it can't be copied to another
+// user.
+//
+// The format of a sealed secret is:
+//
+//	version (1 byte, currently 1) ‖ KEK ID (uvarint) ‖ nonce (12 bytes) ‖ ciphertext and tag
+//
+// Which is 50 bytes for a 20-byte secret with a KEK ID under 128.
+//
+// A Sealer must be created with NewSealer(); the zero value can't be used.
+type Sealer struct {
+	current uint32
+	keys    map[uint32]cipher.AEAD
+}
+
+const sealVersion = 1
+
+// ErrOpen is returned by Sealer.Open() if a secret can't be decrypted: the KEK
+// or user ID is wrong, or the data was modified.
+var ErrOpen = errors.New("otp: can't open sealed secret: wrong key or user ID, or corrupt data")
+
+// NewSealer creates a new Sealer.
+//
+// The keys are the KEKs by ID, and must be 16, 24, or 32 bytes to select
+// AES-128, AES-192, or AES-256. Secrets are sealed with the KEK with ID
+// current, which must be in keys.
+func NewSealer(current uint32, keys map[uint32][]byte) (*Sealer, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("otp.NewSealer: no key with ID %d", current)
+	}
+	s := &Sealer{current: current, keys: make(map[uint32]cipher.AEAD, len(keys))}
+	for id, k := range keys {
+		c, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("otp.NewSealer: key %d: %w", id, err)
+		}
+		s.keys[id], err = cipher.NewGCM(c)
+		if err != nil {
+			return nil, fmt.Errorf("otp.NewSealer: key %d: %w", id, err)
+		}
+	}
+	return s, nil
+}
+
+// Seal encrypts the secret for the given user with the current KEK.
+//
+// Panics if the Sealer wasn't created with NewSealer().
+func (s *Sealer) Seal(secret []byte, userID string) []byte {
+	aead, ok := s.keys[s.current]
+	if !ok {
+		panic("otp.Sealer.Seal: Sealer must be created with NewSealer()")
+	}
+
+	b := make([]byte, 0, 1+binary.MaxVarintLen32+aead.NonceSize()+len(secret)+aead.Overhead())
+	b = append(b, sealVersion)
+	b = binary.AppendUvarint(b, uint64(s.current))
+	header := len(b)
+
+	b = b[:header+aead.NonceSize()]
+	_, _ = rand.Read(b[header:]) // Documented as never returning an error
+	return aead.Seal(b, b[header:], secret, sealAD(b[:header], userID))
+}
+
+// Open decrypts a secret sealed with Seal() for the given user.
+//
+// Returns ErrOpen if it can't be decrypted.
+func (s *Sealer) Open(sealed []byte, userID string) (SecretKey, error) {
+	id, header, err := parseSealed(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("otp.Sealer.Open: %w", err)
+	}
+	aead, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("otp.Sealer.Open: no key with ID %d", id)
+	}
+	if len(sealed) < header+aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("otp.Sealer.Open: sealed secret is too short")
+	}
+
+	nonce, ct := sealed[header:header+aead.NonceSize()], sealed[header+aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ct, sealAD(sealed[:header], userID))
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return secret, nil
+}
+
+// Reseal decrypts a sealed secret and encrypts it again with the current KEK.
+//
+// Use SealedKeyID() to check if a secret needs to be resealed.
+func (s *Sealer) Reseal(sealed []byte, userID string) ([]byte, error) {
+	secret, err := s.Open(sealed, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Destroy()
+	return s.Seal(secret, userID), nil
+}
+
+// SealedKeyID returns the ID of the KEK that was used to seal a secret.
+func SealedKeyID(sealed []byte) (uint32, error) {
+	id, _, err := parseSealed(sealed)
+	if err != nil {
+		return 0, fmt.Errorf("otp.SealedKeyID: %w", err)
+	}
+	return id, nil
+}
+
+// sealAD returns the associated data: the header and user ID.
+func sealAD(header []byte, userID string) []byte {
+	return append(append(make([]byte, 0, len(header)+len(userID)), header...), userID...)
+}
+
+// parseSealed parses the header, returning the KEK ID and header length.
+func parseSealed(sealed []byte) (uint32, int, error) {
+	if len(sealed) == 0 {
+		return 0, 0, errors.New("sealed secret is empty")
+	}
+	if sealed[0] != sealVersion {
+		return 0, 0, fmt.Errorf("unsupported version %d", sealed[0])
+	}
+	id, n := binary.Uvarint(sealed[1:])
+	if n <= 0 || id > 1<<32-1 {
+		return 0, 0, errors.New("invalid key ID")
+	}
+	return uint32(id), 1 + n, nil
+}
@@ -0,0 +1,153 @@
+package otp_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"zgo.at/otp"
+)
+
+var (
+	kek1 = bytes.Repeat([]byte{1}, 32)
+	kek2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func TestSealer(t *testing.T) {
+	s, err := otp.NewSealer(1, map[uint32][]byte{1: kek1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed := s.Seal(secret, "42")
+	if len(sealed) != 50 {
+		t.Errorf("length %d", len(sealed))
+	}
+	if bytes.Contains(sealed, secret) {
+		t.Error("sealed contains secret")
+	}
+	if bytes.Equal(sealed, s.Seal(secret, "42")) {
+		t.Error("nonce is reused")
+	}
+
+	have, err := s.Open(sealed, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, secret) {
+		t.Errorf("\nhave: %x\nwant: %x", []byte(have), secret)
+	}
+
+	if _, err := s.Open(sealed, "43"); !errors.Is(err, otp.ErrOpen) {
+		t.Errorf("wrong user ID: %v", err)
+	}
+	for i := range sealed {
+		tamp := bytes.Clone(sealed)
+		tamp[i] ^= 1
+		if _, err := s.Open(tamp, "42"); err == nil {
+			t.Errorf("no error when modifying byte %d", i)
+		}
+	}
+}
+
+func TestSealerRotate(t *testing.T) {
+	old, err := otp.NewSealer(1, map[uint32][]byte{1: kek1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := old.Seal(secret, "42")
+
+	s, err := otp.NewSealer(300, map[uint32][]byte{1: kek1, 300: kek2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := otp.SealedKeyID(sealed); err != nil || id != 1 {
+		t.Fatalf("SealedKeyID: %d, %v", id, err)
+	}
+	resealed, err := s.Reseal(sealed, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := otp.SealedKeyID(resealed); err != nil || id != 300 {
+		t.Fatalf("SealedKeyID: %d, %v", id, err)
+	}
+
+	// Old key is no longer needed.
+	s, err = otp.NewSealer(300, map[uint32][]byte{300: kek2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := s.Open(resealed, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, secret) {
+		t.Errorf("\nhave: %x\nwant: %x", []byte(have), secret)
+	}
+	if _, err := s.Open(sealed, "42"); !errorContains(err, "no key with ID 1") {
+		t.Errorf("wrong error: %v", err)
+	}
+	if _, err := s.Reseal(sealed, "42"); !errorContains(err, "no key with ID 1") {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestSealerError(t *testing.T) {
+	t.Run("NewSealer", func(t *testing.T) {
+		tests := []struct {
+			current uint32
+			keys    map[uint32][]byte
+			wantErr string
+		}{
+			{1, nil, "no key with ID 1"},
+			{1, map[uint32][]byte{2: kek1}, "no key with ID 1"},
+			{1, map[uint32][]byte{1: kek1, 2: []byte("short")}, "key 2: crypto/aes: invalid key size 5"},
+		}
+		for _, tt := range tests {
+			t.Run("", func(t *testing.T) {
+				_, err := otp.NewSealer(tt.current, tt.keys)
+				if !errorContains(err, tt.wantErr) {
+					t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+				}
+			})
+		}
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		s, err := otp.NewSealer(1, map[uint32][]byte{1: kek1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			in      []byte
+			wantErr string
+		}{
+			{nil, "sealed secret is empty"},
+			{[]byte{2, 1}, "unsupported version 2"},
+			{[]byte{1}, "invalid key ID"},
+			{[]byte{1, 0x80}, "invalid key ID"},
+			{[]byte{1, 0xff, 0xff, 0xff, 0xff, 0x7f}, "invalid key ID"},
+			{[]byte{1, 1, 0, 0}, "sealed secret is too short"},
+		}
+		for _, tt := range tests {
+			t.Run("", func(t *testing.T) {
+				_, err := s.Open(tt.in, "42")
+				if !errorContains(err, tt.wantErr) {
+					t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+				}
+				_, err = otp.SealedKeyID(tt.in)
+				if tt.wantErr != "sealed secret is too short" && !errorContains(err, tt.wantErr) {
+					t.Errorf("SealedKeyID: wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+				}
+			})
+		}
+	})
+	t.Run("zero value", func(t *testing.T) {
+		var s otp.Sealer
+		if _, err := s.Open([]byte{1, 1}, "42"); !errorContains(err, "no key with ID 1") {
+			t.Errorf("wrong error: %v", err)
+		}
+		defer wantPanic(t, "otp.Sealer.Seal: Sealer must be created with NewSealer()")
+		s.Seal(secret, "42")
+	})
+}
@@ -0,0 +1,406 @@
+package otp
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SecretKey is a shared secret that's redacted when printed or logged.
+//
+// It's also redacted by encoding/json and other encoders that use
+// encoding.TextMarshaler, as slog.JSONHandler uses encoding/json for nested
+// values. Use FormatSecret() or convert it to a []byte to store the secret.
+//
+// A SecretKey can be used everywhere a []byte secret is accepted; generators
+// keep their own copy, so it's safe to Destroy() the key after creating a
+// generator.
+type SecretKey []byte
+
+const redacted = "[redacted]"
+
+var (
+	_ fmt.Stringer           = SecretKey{}
+	_ fmt.GoStringer         = SecretKey{}
+	_ fmt.Formatter          = SecretKey{}
+	_ slog.LogValuer         = SecretKey{}
+	_ encoding.TextMarshaler = SecretKey{}
+)
+
+// String returns "[redacted]".
+func (SecretKey) String() string { return redacted }
+
+// GoString returns "otp.SecretKey([redacted])".
+func (SecretKey) GoString() string { return "otp.SecretKey(" + redacted + ")" }
+
+// Format the key as "[redacted]" for all verbs, or GoString() for %#v.
+func (k SecretKey) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		io.WriteString(f, k.GoString())
+		return
+	}
+	io.WriteString(f, k.String())
+}
+
+// LogValue returns "[redacted]".
+func (SecretKey) LogValue() slog.Value { return slog.StringValue(redacted) }
+
+// MarshalText returns "[redacted]".
+func (SecretKey) MarshalText() ([]byte, error) { return []byte(redacted), nil }
+
+// Destroy overwrites the key with zeros.
+//
+// This only clears this copy of the key; the Go runtime may have made other
+// copies (e.g. when growing a slice), and the key may still be in memory
+// elsewhere, e.g. in the string it was parsed from.
+func (k SecretKey) Destroy() { clear(k) }
+
+// SecretOptions are the options for NewSecret().
+type SecretOptions struct {
+	// Hash function the secret will be used with. Default is SHA-1.
+	Hash func() hash.Hash
+
+	// Length in bytes; must be at least 16 (128 bits). The default is the
+	// output size of Hash, as recommended by RFC4226 and used in the RFC6238
+	// test vectors: 20 bytes for SHA-1, 32 for SHA-256, and 64 for SHA-512.
+	Length int
+
+	// Source of randomness. Default is crypto/rand.Reader. This can be set to
+	// get deterministic secrets in tests.
+	Rand io.Reader
+}
+
+// NewSecret generates a new shared secret.
+func NewSecret(opt SecretOptions) ([]byte, error) {
+	if opt.Hash == nil {
+		opt.Hash = sha1.New
+	}
+	if opt.Length == 0 {
+		opt.Length = opt.Hash().Size()
+	}
+	if opt.Rand == nil {
+		opt.Rand = rand.Reader
+	}
+
+	if opt.Length < 16 {
+		return nil, fmt.Errorf("otp.NewSecret: Length must be at least 16 bytes, not %d", opt.Length)
+	}
+	s := make([]byte, opt.Length)
+	if _, err := io.ReadFull(opt.Rand, s); err != nil {
+		return nil, fmt.Errorf("otp.NewSecret: reading random bytes: %w", err)
+	}
+	return s, nil
+}
+
+// SecretEncoding is the text encoding of a secret.
+type SecretEncoding uint8
+
+// Secret encodings.
+const (
+	EncodingAuto     SecretEncoding = iota // Detect the encoding.
+	EncodingBase32                         // RFC 4648 base32, as used in otpauth:// URLs.
+	EncodingHex                            // Hexadecimal, as used by many hardware token vendors.
+	EncodingBase64                         // Standard or URL-safe base64.
+	EncodingPhonetic                       // Base32 spelled with the NATO phonetic alphabet.
+)
+
+func (e SecretEncoding) String() string {
+	switch e {
+	case EncodingAuto:
+		return "auto"
+	case EncodingBase32:
+		return "base32"
+	case EncodingHex:
+		return "hex"
+	case EncodingBase64:
+		return "base64"
+	case EncodingPhonetic:
+		return "phonetic"
+	default:
+		return fmt.Sprintf("SecretEncoding(%d)", uint8(e))
+	}
+}
+
+// ErrShortSecret is returned by ParseSecret() if the secret is shorter than
+// 128 bits.
+var ErrShortSecret = errors.New("secret is shorter than 128 bits")
+
+// ParseSecretOptions are the options for ParseSecretWith().
+type ParseSecretOptions struct {
+	// Encoding of the secret; the default is to detect it.
+	Encoding SecretEncoding
+
+	// Accept secrets shorter than 16 bytes (128 bits), which is the minimum
+	// from RFC4226.
+	AllowShortSecret bool
+}
+
+// ParseSecret parses a secret as entered by a user, detecting the encoding.
+//
+// This is the same as ParseSecretWith() with the default options.
+func ParseSecret(s string) ([]byte, error) {
+	return ParseSecretWith(s, ParseSecretOptions{})
+}
+
+// ParseSecretWith parses a secret as entered by a user.
+//
+// Whitespace is always ignored. For base32 and hex, dashes are ignored as well,
+// case doesn't matter, and '=' padding is optional for base32 and base64. Hex
+// may be prefixed with "0x" or "0X".
+//
+// With EncodingAuto the encoding is detected as:
+//
+//   - phonetic if it's all NATO phonetic words, as written by FormatSecret();
+//   - hex if it's only 0-9 and a-f with an even length;
+//   - base64 if it's mixed case or contains '+', '/', or '_';
+//   - base32 otherwise.
+//
+// Use an explicit encoding if the secret is short, since the shorter it is, the
+// more likely it is that e.g. a base32 secret is also valid hex.
+//
+// An error wrapping ErrShortSecret is returned for secrets shorter than 128
+// bits, unless AllowShortSecret is set. Errors never include the secret.
+func ParseSecretWith(s string, opt ParseSecretOptions) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch opt.Encoding {
+	case EncodingAuto:
+		b, err = parseAuto(s)
+	case EncodingBase32:
+		b, err = parseBase32(s)
+	case EncodingHex:
+		b, err = parseHex(s)
+	case EncodingBase64:
+		b, err = parseBase64(s)
+	case EncodingPhonetic:
+		b, err = parsePhonetic(s)
+	default:
+		panic(fmt.Sprintf("otp.ParseSecretWith: invalid encoding %s", opt.Encoding))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("otp.ParseSecret: %w", err)
+	}
+	if len(b) == 0 {
+		return nil, errors.New("otp.ParseSecret: secret is empty")
+	}
+	if len(b) < 16 && !opt.AllowShortSecret {
+		return nil, fmt.Errorf("otp.ParseSecret: %w: have %d bits", ErrShortSecret, len(b)*8)
+	}
+	return b, nil
+}
+
+const (
+	hexChars    = "0123456789abcdefABCDEF"
+	base32Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz234567"
+	base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/-_"
+)
+
+func parseAuto(s string) ([]byte, error) {
+	if w := phoneticWords(s); len(w) > 1 && allPhonetic(w) {
+		return parsePhonetic(s)
+	}
+	if c, ok := clean(s, hexChars, true); ok && len(c)%2 == 0 {
+		return parseHex(s)
+	}
+	// Base64 is almost always mixed case; anything else is treated as base32,
+	// so that a typo in a base32 secret doesn't get silently decoded as base64.
+	if (strings.ToUpper(s) != s && strings.ToLower(s) != s) || strings.ContainsAny(s, "+/_") {
+		return parseBase64(s)
+	}
+	return parseBase32(s)
+}
+
+func parseBase32(s string) ([]byte, error) {
+	c, ok := clean(s, base32Chars, true)
+	if !ok {
+		return nil, invalidChar(s, base32Chars+"-=", EncodingBase32)
+	}
+	// The decoder silently drops the bits of a trailing partial group.
+	if n := len(c) % 8; n == 1 || n == 3 || n == 6 {
+		return nil, errors.New("invalid base32: wrong length")
+	}
+	b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(c))
+	if err != nil {
+		return nil, errors.New("invalid base32: wrong length")
+	}
+	return b, nil
+}
+
+func parseHex(s string) ([]byte, error) {
+	c, ok := clean(s, hexChars, true)
+	if !ok {
+		return nil, invalidChar(s, hexChars+"-xX", EncodingHex)
+	}
+	b, err := hex.DecodeString(c)
+	if err != nil {
+		return nil, errors.New("invalid hex: odd length")
+	}
+	return b, nil
+}
+
+func parseBase64(s string) ([]byte, error) {
+	c, ok := clean(s, base64Chars, false)
+	if !ok {
+		return nil, invalidChar(s, base64Chars+"=", EncodingBase64)
+	}
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(c, "-_") {
+		enc = base64.RawURLEncoding
+	}
+	b, err := enc.DecodeString(c)
+	if err != nil {
+		return nil, errors.New("invalid base64: wrong length or mixes standard and URL-safe characters")
+	}
+	return b, nil
+}
+
+func parsePhonetic(s string) ([]byte, error) {
+	var b strings.Builder
+	for _, w := range phoneticWords(s) {
+		c, ok := fromPhonetic[strings.ToLower(w)]
+		if !ok {
+			return nil, fmt.Errorf("invalid word %q for phonetic", w)
+		}
+		b.WriteByte(c)
+	}
+	return parseBase32(b.String())
+}
+
+// clean removes whitespace and trailing padding from s, and also dashes if
+// dash is set. It reports whether the remaining characters are all in chars
+// (an "0x" or "0X" prefix is also accepted and removed if chars is hex).
+func clean(s, chars string, dash bool) (string, bool) {
+	s = strings.TrimRight(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || (dash && r == '-') {
+			return -1
+		}
+		return r
+	}, s), "=")
+	if chars == hexChars && len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		s = s[2:]
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(chars, r) {
+			return s, false
+		}
+	}
+	return s, true
+}
+
+// invalidChar returns an error for the first character in s that's not
+// whitespace and not in chars.
+func invalidChar(s, chars string, enc SecretEncoding) error {
+	for i, r := range s {
+		if unicode.IsSpace(r) || strings.ContainsRune(chars, r) {
+			continue
+		}
+		if r == utf8.RuneError {
+			return fmt.Errorf("invalid UTF-8 at position %d", i)
+		}
+		return fmt.Errorf("invalid character %q at position %d for %s", r, i, enc)
+	}
+	return fmt.Errorf("invalid %s", enc)
+}
+
+// NATO phonetic alphabet for the base32 characters.
+var toPhonetic = map[rune]string{
+	'A': "Alfa", 'B': "Bravo", 'C': "Charlie", 'D': "Delta", 'E': "Echo",
+	'F': "Foxtrot", 'G': "Golf", 'H': "Hotel", 'I': "India", 'J': "Juliett",
+	'K': "Kilo", 'L': "Lima", 'M': "Mike", 'N': "November", 'O': "Oscar",
+	'P': "Papa", 'Q': "Quebec", 'R': "Romeo", 'S': "Sierra", 'T': "Tango",
+	'U': "Uniform", 'V': "Victor", 'W': "Whiskey", 'X': "X-ray", 'Y': "Yankee",
+	'Z': "Zulu", '2': "Two", '3': "Three", '4': "Four", '5': "Five", '6': "Six",
+	'7': "Seven",
+}
+
+// fromPhonetic maps lower-case words to base32 characters; this includes
+// common alternative spellings.
+var fromPhonetic = func() map[string]byte {
+	m := map[string]byte{"alpha": 'A', "juliet": 'J', "xray": 'X', "tree": '3', "fower": '4', "fife": '5'}
+	for c, w := range toPhonetic {
+		m[strings.ToLower(w)] = byte(c)
+	}
+	return m
+}()
+
+// phoneticWords splits s in to words on whitespace and commas. "X-ray" is
+// the only word with a dash, so dashes are only treated as a separator if
+// they're not followed by "ray".
+func phoneticWords(s string) []string {
+	s = strings.NewReplacer("-ray", "ray", "-Ray", "Ray", "-RAY", "RAY").Replace(s)
+	return strings.FieldsFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == '-' })
+}
+
+func allPhonetic(words []string) bool {
+	for _, w := range words {
+		if _, ok := fromPhonetic[strings.ToLower(w)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatSecretOptions are the options for FormatSecret().
+type FormatSecretOptions struct {
+	// Split the secret in groups of this many characters, e.g. 4 for "GEZD
+	// GNBV GY3T". Groups are separated by a space, or by ", " when Phonetic is
+	// set.
+	Group int
+
+	// Use lower case.
+	Lower bool
+
+	// Spell every character with the NATO phonetic alphabet ("Golf Echo Zulu
+	// Delta"), for reading it out over the phone.
+	Phonetic bool
+}
+
+// FormatSecret formats a secret as base32 without padding, so users can enter
+// it manually.
+//
+// Every format can be parsed with ParseSecret().
+func FormatSecret(secret []byte, opt FormatSecretOptions) string {
+	if opt.Group < 0 {
+		panic(fmt.Sprintf("otp.FormatSecret: Group must be 0 or greater, not %d", opt.Group))
+	}
+	s := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+
+	var (
+		b        strings.Builder
+		sep, gap = "", " "
+	)
+	if opt.Phonetic {
+		sep, gap = " ", ", "
+	}
+	for i, c := range s {
+		if i > 0 {
+			if opt.Group > 0 && i%opt.Group == 0 {
+				b.WriteString(gap)
+			} else {
+				b.WriteString(sep)
+			}
+		}
+		if opt.Phonetic {
+			b.WriteString(toPhonetic[c])
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	if opt.Lower {
+		return strings.ToLower(b.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,253 @@
+package otp_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+)
+
+func TestSecretKey(t *testing.T) {
+	k := otp.SecretKey("12345678901234567890")
+
+	for _, f := range []string{"%v", "%+v", "%s", "%q", "%x", "%X", "%d", "%10s"} {
+		if have := fmt.Sprintf(f, k); have != "[redacted]" {
+			t.Errorf("%s: %q", f, have)
+		}
+	}
+	if have := fmt.Sprintf("%#v", k); have != "otp.SecretKey([redacted])" {
+		t.Errorf("%%#v: %q", have)
+	}
+	if have := fmt.Sprintf("%v", otp.Key{Secret: k}); strings.Contains(have, "1234") || !strings.Contains(have, "[redacted]") {
+		t.Errorf("Key: %q", have)
+	}
+
+	type user struct{ TOTPSecret otp.SecretKey }
+	buf := new(bytes.Buffer)
+	slog.New(slog.NewJSONHandler(buf, nil)).Info("x", "secret", k, "key", otp.Key{Secret: k}, "user", user{k})
+	slog.New(slog.NewTextHandler(buf, nil)).Info("x", "secret", k)
+	if have := buf.String(); strings.Contains(have, "1234") || strings.Contains(have, "MTIz") || strings.Contains(have, "GEZD") || strings.Count(have, "[redacted]") != 4 {
+		t.Errorf("slog: %s", have)
+	}
+
+	g, err := otp.NewGenerator(otp.GeneratorOptions{Secret: k, Length: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Destroy()
+	if !bytes.Equal(k, make([]byte, 20)) {
+		t.Errorf("not destroyed: %x", []byte(k))
+	}
+	if have, err := g.TokenAt(time.Unix(59, 0)); err != nil || have != "94287082" {
+		t.Errorf("generator doesn't have its own copy: %q", have)
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	rnd := bytes.Repeat([]byte("1234567890"), 10)
+
+	tests := []struct {
+		opt     otp.SecretOptions
+		want    string
+		wantErr string
+	}{
+		{otp.SecretOptions{Rand: bytes.NewReader(rnd)}, string(secret), ""},
+		{otp.SecretOptions{Rand: bytes.NewReader(rnd), Hash: sha256.New}, string(secret256), ""},
+		{otp.SecretOptions{Rand: bytes.NewReader(rnd), Hash: sha512.New}, string(secret512), ""},
+		{otp.SecretOptions{Rand: bytes.NewReader(rnd), Hash: sha512.New, Length: 16}, "1234567890123456", ""},
+
+		{otp.SecretOptions{Length: 10}, "", "Length must be at least 16 bytes, not 10"},
+		{otp.SecretOptions{Rand: bytes.NewReader(rnd[:19])}, "", "reading random bytes: unexpected EOF"},
+		{otp.SecretOptions{Rand: strings.NewReader("")}, "", "reading random bytes: EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := otp.NewSecret(tt.opt)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if string(have) != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+		})
+	}
+
+	t.Run("crypto/rand", func(t *testing.T) {
+		one, err := otp.NewSecret(otp.SecretOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		two, err := otp.NewSecret(otp.SecretOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(one) != 20 || len(two) != 20 || bytes.Equal(one, two) {
+			t.Errorf("\none: %x\ntwo: %x", one, two)
+		}
+	})
+
+	t.Run("ErrUnexpectedEOF", func(t *testing.T) {
+		_, err := otp.NewSecret(otp.SecretOptions{Rand: io.LimitReader(bytes.NewReader(rnd), 5)})
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("wrong error: %v", err)
+		}
+	})
+}
+
+func TestParseSecret(t *testing.T) {
+	tests := []struct {
+		in      string
+		enc     otp.SecretEncoding
+		want    string
+		wantErr string
+	}{
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", otp.EncodingAuto, string(secret), ""},
+		{"gezd gnbv gy3t qojq gezd gnbv gy3t qojq", otp.EncodingAuto, string(secret), ""},
+		{"GEZD-GNBV-GY3T-QOJQ-GEZD-GNBV-GY3T-QOJQ", otp.EncodingAuto, string(secret), ""},
+		{"  GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====\n", otp.EncodingAuto, string(secret), ""},
+		{"3132333435363738393031323334353637383930", otp.EncodingAuto, string(secret), ""},
+		{"0x31323334 35363738 39303132 33343536 37383930", otp.EncodingAuto, string(secret), ""},
+		{"0X3132333435363738393031323334353637383930", otp.EncodingAuto, string(secret), ""},
+		{"0X3132333435363738393031323334353637383930", otp.EncodingHex, string(secret), ""},
+		{"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=", otp.EncodingAuto, string(secret), ""},
+		{"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA", otp.EncodingAuto, string(secret), ""},
+		{"_-8_-8_-8_-8_-8_-8_-8_", otp.EncodingAuto, "\xff\xef?\xfb\xcf\xfe\xf3\xff\xbc\xff\xef?\xfb\xcf\xfe\xf3", ""},
+
+		// Valid hex, but explicitly base32.
+		{"ABCDEF234567ABCDEF234567ABCDEF23", otp.EncodingBase32, "\x00D2\x17[\xe7}\xf0\x04C!u\xbew\xdf\x00D2\x17[", ""},
+		{"3132333435363738393031323334353637383930", otp.EncodingHex, string(secret), ""},
+		{"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=", otp.EncodingBase64, string(secret), ""},
+
+		{"", otp.EncodingAuto, "", "secret is empty"},
+		{"   ", otp.EncodingAuto, "", "secret is empty"},
+		{"GEZDGNBVGY3TQOJQ", otp.EncodingAuto, "", "secret is shorter than 128 bits: have 80 bits"},
+		{"GEZDGNBVGY3TQOJQGEZD0NBVGY3TQOJQ", otp.EncodingAuto, "", `invalid character '0' at position 20 for base32`},
+		{"GEZDGNBVGY3TQOJQGEZD!NBVGY3TQOJQ", otp.EncodingAuto, "", `invalid character '!' at position 20 for base32`},
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQA", otp.EncodingAuto, "", "invalid base32: wrong length"},
+		{"313233343536373839303132333435363738393", otp.EncodingHex, "", "invalid hex: odd length"},
+		{"3132333435363738393031323334353637383g30", otp.EncodingHex, "", `invalid character 'g' at position 37 for hex`},
+		{"MTIzNDU2Nzg5MDEy.zQ1Njc4OTA", otp.EncodingAuto, "", `invalid character '.' at position 16 for base64`},
+		{"MTIzNDU2Nzg5MDEyM", otp.EncodingBase64, "", "invalid base64"},
+		{"MTIzNDU2Nzg5MDEy\xffzQ1Njc4OTA", otp.EncodingAuto, "", "invalid UTF-8 at position 16"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := otp.ParseSecretWith(tt.in, otp.ParseSecretOptions{Encoding: tt.enc})
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if string(have) != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+			if err != nil && len(tt.in) > 8 && strings.Contains(err.Error(), tt.in[:8]) {
+				t.Errorf("error contains secret: %s", err)
+			}
+		})
+	}
+}
+
+func TestParseSecretShort(t *testing.T) {
+	_, err := otp.ParseSecret("GEZDGNBVGY3TQOJQ")
+	if !errors.Is(err, otp.ErrShortSecret) {
+		t.Fatalf("wrong error: %v", err)
+	}
+
+	have, err := otp.ParseSecretWith("GEZDGNBVGY3TQOJQ", otp.ParseSecretOptions{AllowShortSecret: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(have) != "1234567890" {
+		t.Errorf("have: %q", have)
+	}
+}
+
+func TestFormatSecret(t *testing.T) {
+	tests := []struct {
+		opt  otp.FormatSecretOptions
+		want string
+	}{
+		{otp.FormatSecretOptions{}, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		{otp.FormatSecretOptions{Group: 4}, "GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ"},
+		{otp.FormatSecretOptions{Group: 4, Lower: true}, "gezd gnbv gy3t qojq gezd gnbv gy3t qojq"},
+		{otp.FormatSecretOptions{Group: 5}, "GEZDG NBVGY 3TQOJ QGEZD GNBVG Y3TQO JQ"},
+		{otp.FormatSecretOptions{Phonetic: true}, "Golf Echo Zulu Delta Golf November Bravo Victor Golf Yankee Three Tango " +
+			"Quebec Oscar Juliett Quebec Golf Echo Zulu Delta Golf November Bravo Victor Golf Yankee Three Tango " +
+			"Quebec Oscar Juliett Quebec"},
+		{otp.FormatSecretOptions{Phonetic: true, Group: 4, Lower: true}, "golf echo zulu delta, golf november bravo victor, " +
+			"golf yankee three tango, quebec oscar juliett quebec, golf echo zulu delta, golf november bravo victor, " +
+			"golf yankee three tango, quebec oscar juliett quebec"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have := otp.FormatSecret(secret, tt.opt)
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+			parsed, err := otp.ParseSecret(have)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(parsed) != string(secret) {
+				t.Errorf("round-trip:\nhave: %q\nwant: %q", parsed, secret)
+			}
+		})
+	}
+}
+
+func TestFormatSecretRoundTrip(t *testing.T) {
+	opts := []otp.FormatSecretOptions{
+		{}, {Group: 4}, {Lower: true}, {Group: 3, Lower: true},
+		{Phonetic: true}, {Phonetic: true, Group: 4}, {Phonetic: true, Lower: true},
+	}
+	for i := 0; i < 200; i++ {
+		s := otp.Secret()
+		for _, opt := range opts {
+			f := otp.FormatSecret(s, opt)
+			have, err := otp.ParseSecret(f)
+			if err != nil {
+				t.Fatalf("%+v: %s", opt, err)
+			}
+			if string(have) != string(s) {
+				t.Fatalf("%+v: %q\nhave: %x\nwant: %x", opt, f, have, s)
+			}
+		}
+	}
+}
+
+func TestParseSecretPhonetic(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"ALPHA-BRAVO-X-RAY-XRAY Tree Fower Fife Juliet ALFA, BRAVO, X-RAY, Xray Three Four Five Juliett", "\x00o}\xf3\xa9\x00o}\xf3\xa9", ""},
+		{"Golf Echo Zulu Delta Golf November Bravo Victor Golf Yankee Three Tango Quebec Oscar Juliett Quebec", "", "secret is shorter than 128 bits"},
+	}
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, err := otp.ParseSecretWith(tt.in, otp.ParseSecretOptions{AllowShortSecret: tt.wantErr == ""})
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if string(have) != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+		})
+	}
+
+	_, err := otp.ParseSecretWith("Golf Echo Zoolu", otp.ParseSecretOptions{Encoding: otp.EncodingPhonetic})
+	if !errorContains(err, `invalid word "Zoolu" for phonetic`) {
+		t.Errorf("wrong error: %v", err)
+	}
+}
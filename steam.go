@@ -0,0 +1,50 @@
+package otp
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NewSteam returns a generator for Steam Guard tokens.
+//
+// Steam uses TOTP with SHA-1 and a 30 second step, but encodes tokens as 5
+// characters with the Steam() encoder rather than as digits. Verify() accepts
+// lower-case tokens.
+//
+// Providing the time can be useful to provide a fixed time for testing. It uses
+// time.Now() if nil.
+//
+// Panics if sharedSecret is empty.
+func NewSteam(sharedSecret []byte, t func() time.Time) Generator {
+	if len(sharedSecret) == 0 {
+		panic("otp.NewSteam: sharedSecret must not be empty")
+	}
+	g := NewTOTP(sharedSecret, 5, sha1.New, TOTPConfig{Now: t})
+	g.enc = Steam()
+	return g
+}
+
+// ParseSteamSecret parses a secret in the form "steam://SECRET", where the
+// secret is base32-encoded. This form is used by several password managers.
+//
+// Errors never include the secret.
+func ParseSteamSecret(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 8 || !strings.EqualFold(s[:8], "steam://") {
+		return nil, errors.New("otp.ParseSteamSecret: doesn't start with steam://")
+	}
+	b, err := decodeBase32(s[8:])
+	if err != nil {
+		return nil, fmt.Errorf("otp.ParseSteamSecret: %w", err)
+	}
+	return b, nil
+}
+
+// decodeBase32 decodes a base32 secret from an existing key; short secrets are
+// accepted, as they're common.
+func decodeBase32(s string) ([]byte, error) {
+	return ParseSecretWith(s, ParseSecretOptions{Encoding: EncodingBase32, AllowShortSecret: true})
+}
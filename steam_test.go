@@ -0,0 +1,73 @@
+package otp_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+)
+
+func TestSteam(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Unix(0, 0), "GG5F5"},
+		{time.Unix(59, 0), "PV9M4"},
+		{time.Unix(1111111109, 0), "PY4YB"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			o := otp.NewSteam(secret, func() time.Time { return tt.t })
+			have := o.Token(0)
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+			if have, err := o.TokenAt(tt.t); err != nil || have != tt.want {
+				t.Errorf("TokenAt\nhave: %q\nwant: %q", have, tt.want)
+			}
+			if !o.Verify(tt.want, 0) {
+				t.Error("Verify() failed")
+			}
+			if !o.Verify(strings.ToLower(tt.want), 0) {
+				t.Error("Verify() failed for lower-case")
+			}
+			if _, err := o.VerifyDetailed("AAAAA", 1); !errors.Is(err, otp.ErrInvalidFormat) {
+				t.Errorf("wrong error for invalid alphabet: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSteamSecret(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []byte
+		wantErr string
+	}{
+		{"steam://GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", secret, ""},
+		{" steam://gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", secret, ""},
+		{"steam://GEZDGNBV GY3TQOJQ GEZDGNBV GY3TQOJQ====", secret, ""},
+		{"STEAM://GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", secret, ""},
+
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", nil, "doesn't start with steam://"},
+		{"steam://", nil, "secret is empty"},
+		{"steam://GEZ1", nil, "invalid character '1' at position 3 for base32"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			have, err := otp.ParseSteamSecret(tt.in)
+			if !errorContains(err, tt.wantErr) {
+				t.Fatalf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(have, tt.want) {
+				t.Errorf("\nhave: %x\nwant: %x", have, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package otp
+
+import (
+	"hash"
+	"math/big"
+	"time"
+)
+
+// TOTPConfig is the configuration for TOTP tokens, as defined in RFC6238.
+type TOTPConfig struct {
+	// T0 is the time to start counting steps from. The Unix epoch is used if
+	// this is the zero value.
+	T0 time.Time
+
+	// Period is the time step that tokens are valid for. It will use 30 seconds
+	// if zero.
+	Period time.Duration
+
+	// Now returns the current time. It uses time.Now() if nil.
+	Now func() time.Time
+}
+
+// Counter returns a counter function to generate TOTP tokens.
+func (c TOTPConfig) Counter() CounterFunc {
+	now := c.Now
+	if now == nil {
+		now = time.Now
+	}
+	return func(offset int) uint64 {
+		return uint64(c.Step(now()) + int64(offset))
+	}
+}
+
+// CounterAt returns the counter for the time t.
+//
+// This is the same as Step(), except that steps before T0 wrap around.
+func (c TOTPConfig) CounterAt(t time.Time) uint64 {
+	return uint64(c.Step(t))
+}
+
+// Step returns the time step for the time t: the number of periods since T0.
+//
+// This is negative for times before T0. The step for a time that's exactly on
+// a period boundary is the step that starts at that time.
+func (c TOTPConfig) Step(t time.Time) int64 {
+	var (
+		t0   = c.t0()
+		p    = int64(c.period())
+		secs = t.Unix() - t0.Unix()
+		nsec = int64(t.Nanosecond() - t0.Nanosecond())
+	)
+	if nsec < 0 {
+		secs, nsec = secs-1, nsec+1e9
+	}
+
+	// Fast path for the common case of a period that's a whole number of
+	// seconds; steps always start on a whole second, so the nanoseconds can
+	// be ignored.
+	if p%1e9 == 0 {
+		return floorDiv(secs, p/1e9)
+	}
+
+	d := new(big.Int).Mul(big.NewInt(secs), big.NewInt(1e9))
+	d.Add(d, big.NewInt(nsec))
+	return d.Div(d, big.NewInt(p)).Int64() // Euclidean division, so rounds down.
+}
+
+// StepStart returns the time the time step starts.
+func (c TOTPConfig) StepStart(step int64) time.Time {
+	var (
+		t0 = c.t0()
+		p  = int64(c.period())
+	)
+	if p%1e9 == 0 {
+		return time.Unix(t0.Unix()+step*(p/1e9), int64(t0.Nanosecond()))
+	}
+
+	var (
+		d    = new(big.Int).Mul(big.NewInt(step), big.NewInt(p))
+		nsec = new(big.Int)
+	)
+	d.DivMod(d, big.NewInt(1e9), nsec)
+	return time.Unix(t0.Unix()+d.Int64(), int64(t0.Nanosecond())+nsec.Int64())
+}
+
+// NextRollover returns the time the step after the step for t starts, which is
+// when a new token will be generated.
+func (c TOTPConfig) NextRollover(t time.Time) time.Time {
+	return c.StepStart(c.Step(t) + 1)
+}
+
+// Remaining returns the time until the next rollover, which is how long the
+// token for t remains valid.
+func (c TOTPConfig) Remaining(t time.Time) time.Duration {
+	return c.NextRollover(t).Sub(t)
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func (c TOTPConfig) period() time.Duration {
+	if c.Period == 0 {
+		return 30 * time.Second
+	}
+	return c.Period
+}
+
+func (c TOTPConfig) t0() time.Time {
+	if c.T0.IsZero() {
+		return time.Unix(0, 0)
+	}
+	return c.T0
+}
+
+// NewTOTP returns a generator for TOTP tokens.
+//
+// This is the same as New() with c.Counter(), but the generator also keeps the
+// configuration, which is needed for TokenAt() and VerifyAt().
+//
+// Panics if tokenLength is <= 0 or if any of the other parameters are nil.
+func NewTOTP(sharedSecret []byte, tokenLength int, hash func() hash.Hash, c TOTPConfig) Generator {
+	g := New(sharedSecret, tokenLength, hash, c.Counter())
+	g.totp = &c
+	return g
+}
+
+// TokenAt generates the token for the time t.
+//
+// Returns ErrNotTOTP if the generator wasn't created with NewTOTP(),
+// NewGenerator(), or NewSteam().
+func (g Generator) TokenAt(t time.Time) (string, error) {
+	at, err := g.at(t)
+	if err != nil {
+		return "", err
+	}
+	return at.Token(0), nil
+}
+
+// VerifyAt verifies a token as if the current time is t, like
+// VerifyDetailed().
+//
+// Returns ErrNotTOTP if the generator wasn't created with NewTOTP(),
+// NewGenerator(), or NewSteam().
+func (g Generator) VerifyAt(token string, t time.Time, offset int) (VerifyResult, error) {
+	at, err := g.at(t)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	return at.VerifyDetailed(token, offset)
+}
+
+func (g Generator) at(t time.Time) (Generator, error) {
+	if g.totp == nil {
+		return Generator{}, ErrNotTOTP
+	}
+	c := *g.totp
+	c.Now = func() time.Time { return t }
+	g.counter = c.Counter()
+	return g, nil
+}
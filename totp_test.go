@@ -0,0 +1,146 @@
+package otp_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+)
+
+func TestTokenAt(t *testing.T) {
+	tests := []struct {
+		opt  otp.GeneratorOptions
+		t    time.Time
+		want string
+	}{
+		// From RFC 6238 Appendix B
+		{otp.GeneratorOptions{Secret: secret, Length: 8}, time.Unix(59, 0), "94287082"},
+		{otp.GeneratorOptions{Secret: secret256, Length: 8, Hash: sha256.New}, time.Unix(1111111109, 0), "68084774"},
+		{otp.GeneratorOptions{Secret: secret, Length: 8}, time.Unix(20000000000, 0), "65353130"},
+
+		// Non-zero T0
+		{otp.GeneratorOptions{Secret: secret, Length: 8, T0: time.Unix(3000, 0)}, time.Unix(3059, 0), "94287082"},
+		{otp.GeneratorOptions{Secret: secret, Length: 8, T0: time.Unix(1000, 0)}, time.Unix(1111112109, 0), "07081804"},
+
+		// Non-default period
+		{otp.GeneratorOptions{Secret: secret, Length: 8, Period: time.Minute}, time.Unix(119, 0), "94287082"},
+		{otp.GeneratorOptions{Secret: secret, Length: 8, Period: time.Minute, T0: time.Unix(60, 0)}, time.Unix(179, 0), "94287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			g, err := otp.NewGenerator(tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			have, err := g.TokenAt(tt.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+			if _, err := g.VerifyAt(tt.want, tt.t, 0); err != nil {
+				t.Errorf("VerifyAt() failed: %v", err)
+			}
+			if _, err := g.VerifyAt(tt.want, tt.t.Add(30*time.Second), 1); err != nil {
+				t.Errorf("VerifyAt() with offset failed: %v", err)
+			}
+			if _, err := g.VerifyAt(tt.want, tt.t.Add(-time.Hour), 1); !errors.Is(err, otp.ErrNoMatch) {
+				t.Errorf("VerifyAt() for wrong time: %v", err)
+			}
+		})
+	}
+}
+
+func TestTOTPConfig(t *testing.T) {
+	now := func() time.Time { return time.Unix(1111111109, 0) }
+	o := otp.New(secret, 8, sha1.New, otp.TOTPConfig{Now: now}.Counter())
+	if have := o.Token(0); have != "07081804" {
+		t.Errorf("have %q", have)
+	}
+
+	o = otp.NewTOTP(secret, 8, sha1.New, otp.TOTPConfig{Now: now, T0: time.Unix(30, 0)})
+	if have := o.Token(1); have != "07081804" {
+		t.Errorf("have %q", have)
+	}
+	if have, _ := o.TokenAt(time.Unix(1111111139, 0)); have != "07081804" {
+		t.Errorf("TokenAt: have %q", have)
+	}
+
+	c := otp.TOTPConfig{Period: time.Minute, T0: time.Unix(60, 0)}
+	if have := c.CounterAt(time.Unix(179, 0)); have != 1 {
+		t.Errorf("have %d", have)
+	}
+}
+
+func TestTokenAtNotTOTP(t *testing.T) {
+	g, err := otp.NewGenerator(otp.GeneratorOptions{Secret: secret, Counter: otp.HOTP(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, g := range []otp.Generator{*g, otp.New(secret, 6, sha1.New, otp.TOTP(0, nil))} {
+		if _, err := g.TokenAt(time.Now()); !errors.Is(err, otp.ErrNotTOTP) {
+			t.Errorf("TokenAt: wrong error: %v", err)
+		}
+		if _, err := g.VerifyAt("755224", time.Now(), 0); !errors.Is(err, otp.ErrNotTOTP) {
+			t.Errorf("VerifyAt: wrong error: %v", err)
+		}
+	}
+}
+
+func TestTOTPConfigStep(t *testing.T) {
+	tests := []struct {
+		c         otp.TOTPConfig
+		t         time.Time
+		step      int64
+		start     time.Time
+		remaining time.Duration
+	}{
+		{otp.TOTPConfig{}, time.Unix(0, 0), 0, time.Unix(0, 0), 30 * time.Second},
+		{otp.TOTPConfig{}, time.Unix(59, 0), 1, time.Unix(30, 0), 1 * time.Second},
+		{otp.TOTPConfig{}, time.Unix(59, 999_999_999), 1, time.Unix(30, 0), 1},
+		{otp.TOTPConfig{}, time.Unix(60, 0), 2, time.Unix(60, 0), 30 * time.Second},
+		{otp.TOTPConfig{}, time.Unix(1111111109, 0), 0x23523ec, time.Unix(1111111110-30, 0), 1 * time.Second},
+		{otp.TOTPConfig{}, time.Unix(20000000000, 0), 0x27bc86aa, time.Unix(19999999980, 0), 10 * time.Second},
+		{otp.TOTPConfig{Period: time.Minute}, time.Unix(119, 0), 1, time.Unix(60, 0), time.Second},
+
+		// Before T0
+		{otp.TOTPConfig{}, time.Unix(-1, 0), -1, time.Unix(-30, 0), time.Second},
+		{otp.TOTPConfig{}, time.Unix(-30, 0), -1, time.Unix(-30, 0), 30 * time.Second},
+		{otp.TOTPConfig{}, time.Unix(-31, 0), -2, time.Unix(-60, 0), time.Second},
+		{otp.TOTPConfig{}, time.Unix(-1, 500_000_000), -1, time.Unix(-30, 0), 500 * time.Millisecond},
+		{otp.TOTPConfig{T0: time.Unix(100, 0)}, time.Unix(99, 0), -1, time.Unix(70, 0), time.Second},
+		{otp.TOTPConfig{T0: time.Unix(100, 0)}, time.Unix(130, 0), 1, time.Unix(130, 0), 30 * time.Second},
+
+		// Sub-second T0 and period
+		{otp.TOTPConfig{T0: time.Unix(0, 500_000_000)}, time.Unix(30, 0), 0, time.Unix(0, 500_000_000), 500 * time.Millisecond},
+		{otp.TOTPConfig{Period: 1500 * time.Millisecond}, time.Unix(3, 0), 2, time.Unix(3, 0), 1500 * time.Millisecond},
+		{otp.TOTPConfig{Period: 1500 * time.Millisecond}, time.Unix(-1, 0), -1, time.Unix(-2, 500_000_000), time.Second},
+		{otp.TOTPConfig{Period: 1500 * time.Millisecond}, time.Unix(20000000000, 0), 13333333333, time.Unix(19999999999, 500_000_000), time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			if have := tt.c.Step(tt.t); have != tt.step {
+				t.Errorf("Step: have %d; want %d", have, tt.step)
+			}
+			if have := tt.c.CounterAt(tt.t); have != uint64(tt.step) {
+				t.Errorf("CounterAt: have %d; want %d", have, uint64(tt.step))
+			}
+			if have := tt.c.StepStart(tt.step); !have.Equal(tt.start) {
+				t.Errorf("StepStart: have %s; want %s", have, tt.start)
+			}
+			if have := tt.c.Remaining(tt.t); have != tt.remaining {
+				t.Errorf("Remaining: have %s; want %s", have, tt.remaining)
+			}
+			if have, want := tt.c.NextRollover(tt.t), tt.t.Add(tt.remaining); !have.Equal(want) {
+				t.Errorf("NextRollover: have %s; want %s", have, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package twofas_test
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/otp"
+	"zgo.at/otp/twofas"
+)
+
+const plain = `{
+	"services": [
+		{
+			"name": "Example",
+			"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			"updatedAt": 1700000000000,
+			"otp": {"label": "alice@example.com", "account": "alice@example.com", "issuer": "Example",
+				"digits": 6, "period": 30, "algorithm": "SHA1", "counter": 0, "tokenType": "TOTP", "source": "Link"},
+			"order": {"position": 0},
+			"icon": {"selected": "Label", "label": {"text": "EX", "backgroundColor": "Orange"}}
+		},
+		{
+			"name": "Bank",
+			"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			"updatedAt": 1700000000000,
+			"otp": {"label": "bob", "account": "", "issuer": "",
+				"digits": 8, "period": 30, "algorithm": "SHA512", "counter": 7, "tokenType": "HOTP", "source": "Manual"},
+			"order": {"position": 1}
+		},
+		{
+			"name": "Steam",
+			"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			"updatedAt": 1700000000000,
+			"otp": {"account": "carol", "issuer": "Steam", "digits": 5, "period": 30, "algorithm": "SHA1", "tokenType": "STEAM"},
+			"order": {"position": 2}
+		}
+	],
+	"groups": [],
+	"updatedAt": 1700000000000,
+	"schemaVersion": 4,
+	"appVersionCode": 5000000,
+	"appVersionName": "5.0.0",
+	"appOrigin": "android"
+}`
+
+var (
+	secret = []byte("12345678901234567890")
+	want   = []twofas.Entry{
+		{Key: otp.Key{Kind: otp.KindTOTP, Secret: secret, Issuer: "Example", Account: "alice@example.com",
+			Algorithm: crypto.SHA1, Digits: 6, Period: 30 * time.Second}, Name: "Example"},
+		{Key: otp.Key{Kind: otp.KindHOTP, Secret: secret, Account: "bob",
+			Algorithm: crypto.SHA512, Digits: 8, Counter: 7}, Name: "Bank"},
+		{Key: otp.Key{Kind: otp.KindSteam, Secret: secret, Issuer: "Steam", Account: "carol",
+			Algorithm: crypto.SHA1, Digits: 5, Period: 30 * time.Second}, Name: "Steam"},
+	}
+)
+
+func TestRead(t *testing.T) {
+	have, err := twofas.Read(strings.NewReader(plain), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+	}
+
+	g, err := have[0].Key.Generator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := g.TokenAt(time.Unix(59, 0)); err != nil || tok != "287082" {
+		t.Errorf("wrong token: %q", tok)
+	}
+}
+
+// testdata/encrypted.2fas is plain encrypted with the password "test". It wasn't
+// created with Write(), but independently from the 2FAS backup format, to
+// catch mistakes that Write() and Read() would share.
+func TestReadEncrypted(t *testing.T) {
+	data, err := os.ReadFile("testdata/encrypted.2fas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := twofas.Read(bytes.NewReader(data), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+	}
+
+	_, err = twofas.Read(bytes.NewReader(data), "test2")
+	if err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("wrong error for wrong password: %v", err)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	tests := []struct {
+		in, wantErr string
+	}{
+		{strings.Replace(plain, `"tokenType": "HOTP"`, `"tokenType": "YANDEX"`, 1), `service 1 ("Bank"): unsupported token type "YANDEX"`},
+		{strings.Replace(plain, `"SHA512"`, `"MD5"`, 1), `unsupported algorithm "MD5"`},
+		{strings.Replace(plain, `"digits": 8`, `"digits": 12`, 1), `invalid digits 12`},
+		{strings.Replace(plain, `"counter": 7, `, ``, 1), `service 1 ("Bank"): counter is missing`},
+		{strings.Replace(plain, `GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ`, `1`, 1), `otp.ParseSecret: invalid character '1'`},
+		{strings.Replace(plain, `"schemaVersion": 4`, `"schemaVersion": 5`, 1), `unsupported schema version 5`},
+		{`{"services": [], "schemaVersion": 4, "servicesEncrypted": "a:b:c"}`, `password is empty`},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			_, err := twofas.Read(strings.NewReader(tt.in), "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, password := range []string{"", "hunter2"} {
+		t.Run(password, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := twofas.Write(&buf, want, password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isEnc := !bytes.Contains(buf.Bytes(), []byte("GEZDGNBV")); isEnc != (password != "") {
+				t.Fatalf("encrypted=%t; password=%q\n%s", isEnc, password, buf.String())
+			}
+
+			have, err := twofas.Read(bytes.NewReader(buf.Bytes()), password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, want)
+			}
+
+			if password != "" {
+				_, err := twofas.Read(bytes.NewReader(buf.Bytes()), "hunter3")
+				if err == nil || err.Error() != "twofas.Read: wrong password" {
+					t.Errorf("wrong error for wrong password: %v", err)
+				}
+
+				// Modify the services but not the reference.
+				var v map[string]any
+				_ = json.Unmarshal(buf.Bytes(), &v)
+				parts := strings.Split(v["servicesEncrypted"].(string), ":")
+				ct, err := base64.StdEncoding.DecodeString(parts[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				ct[0] ^= 1
+				parts[0] = base64.StdEncoding.EncodeToString(ct)
+				v["servicesEncrypted"] = strings.Join(parts, ":")
+				tamp, _ := json.Marshal(v)
+				_, err = twofas.Read(bytes.NewReader(tamp), password)
+				if err == nil || !strings.Contains(err.Error(), "corrupt backup") {
+					t.Errorf("wrong error for modified backup: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		in      otp.Key
+		wantErr string
+	}{
+		{otp.Key{}, "secret is empty"},
+		{otp.Key{Secret: secret, Algorithm: crypto.MD5}, "algorithm MD5 not supported"},
+		{otp.Key{Secret: secret, T0: time.Unix(1, 0)}, "T0 not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			err := twofas.Write(new(bytes.Buffer), []twofas.Entry{{Key: tt.in}}, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("wrong error\nhave: %v\nwant: %v", err, tt.wantErr)
+			}
+		})
+	}
+}